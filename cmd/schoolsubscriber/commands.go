@@ -0,0 +1,501 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/eldarbr/schoolsubscriber/internal/calfeed"
+	"github.com/eldarbr/schoolsubscriber/internal/client/tgbot"
+	"github.com/eldarbr/schoolsubscriber/internal/domain"
+	"github.com/eldarbr/schoolsubscriber/internal/timeranges"
+)
+
+const (
+	commandWatch = "watch"
+
+	// bookSearchSpan bounds the review length the book command looks for.
+	bookSearchSpan = 4 * time.Hour
+)
+
+var (
+	ErrBadArgs      = errors.New("wrong arguments")
+	ErrSlotNotFound = errors.New("no available slot starts at the time")
+	ErrExportOff    = errors.New("the export file is not configured")
+	ErrDoctor       = errors.New("some checks failed")
+)
+
+type command struct {
+	args string
+	help string
+	run  func(ctx context.Context, flags globalFlags, args []string) error
+}
+
+//nolint:gochecknoglobals // the command table.
+var commands = map[string]command{
+	commandWatch:  {args: "", help: "search and book the slots until stopped, the default", run: runWatch},
+	"goals":       {args: "", help: "list the current goals", run: runGoals},
+	"slots":       {args: "", help: "print the slots found for the chosen goals, nothing is booked", run: runSlots},
+	"plan":        {args: "", help: "print the planner schedule for the chosen goals, nothing is booked", run: runPlan},
+	"book":        {args: "<goal> <time>", help: "book the slot starting at the time", run: runBook},
+	"bookings":    {args: "", help: "list the bookings from the export file", run: runBookings},
+	"profile":     {args: "", help: "print the public profile", run: runProfile},
+	"evaluations": {args: "", help: "print the peer reviews of the chosen goals", run: runEvaluations},
+	"doctor":      {args: "", help: "check the config, the login and the bot", run: runDoctor},
+}
+
+//nolint:gochecknoglobals // the help order.
+var commandOrder = []string{
+	commandWatch, "goals", "slots", "plan", "book", "bookings", "profile", "evaluations", "doctor",
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+
+	fmt.Fprintf(out, "Usage: %s [flags] [command] [args]\n\nCommands:\n", os.Args[0])
+
+	for _, name := range commandOrder {
+		fmt.Fprintf(out, "  %-25s %s\n", name+" "+commands[name].args, commands[name].help)
+	}
+
+	fmt.Fprintln(out, "\nFlags:")
+	flag.PrintDefaults()
+}
+
+func runWatch(ctx context.Context, flags globalFlags, _ []string) error {
+	flags.out.stream = true
+
+	conf, loc, err := loadConfig(flags, true)
+	if err != nil {
+		return err
+	}
+
+	var bot domain.Notificator
+
+	if conf.Bot != nil {
+		bot = tgbot.NewBot(conf.Bot.Token, conf.Bot.ChatID)
+		err = bot.SendMessage(ctx, "Hi! Searching slots")
+		if err != nil {
+			log.Println("Err bot initialization message:", err)
+			bot = nil
+		}
+	}
+
+	client, err := connect(ctx, flags, conf, bot)
+	if err != nil {
+		return err
+	}
+
+	goals, err := selectGoals(ctx, client, conf, flags)
+	if errors.Is(err, ErrNothingToCheck) {
+		log.Println("No goals to review :)")
+
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	found, err := newSearch(ctx, client, conf, loc, goals, flags, true)
+	if err != nil {
+		return err
+	}
+
+	if len(found.worker.schedule.Ranges.Rules) < 1 && !conf.Goals.hasRanges() {
+		return fmt.Errorf("parse time ranges: %w", ErrNoRanges)
+	}
+
+	if !flags.out.json {
+		windows, _ := found.worker.schedule.Ranges.Windows(time.Now())
+		PrintRanges(windows)
+	}
+
+	group := sync.WaitGroup{}
+
+	if conf.Planner {
+		found.worker.plannerWorker(ctx, found.params, found.summaries)
+	} else {
+		for i := range found.params {
+			group.Add(1)
+
+			go found.worker.attemptWorker(ctx, found.params[i], &found.summaries[i], &group)
+		}
+	}
+
+	group.Wait()
+	client.Flush()
+
+	log.Println("Shutting down")
+
+	report := formatSummaries(found.summaries)
+	found.worker.printSummaries(found.summaries, report)
+
+	log.Println("Requests to the platform:")
+	logRequests(flags.metrics)
+
+	if bot != nil {
+		botCtx, botCtxCancel := context.WithTimeout(context.WithoutCancel(ctx), finalMessageTimeout)
+		defer botCtxCancel()
+
+		err = bot.SendMessage(botCtx, "Bye! Stopped searching slots\n"+report)
+		if err != nil {
+			log.Println("Err bot final message:", err)
+		}
+	}
+
+	return nil
+}
+
+func runGoals(ctx context.Context, flags globalFlags, _ []string) error {
+	conf, _, err := loadConfig(flags, false)
+	if err != nil {
+		return err
+	}
+
+	client, err := connect(ctx, flags, conf, nil)
+	if err != nil {
+		return err
+	}
+
+	goals, err := client.GetCurrentGoals(ctx)
+	if err != nil {
+		return fmt.Errorf("get current goals: %w", err)
+	}
+
+	if flags.out.json {
+		for _, goal := range goals {
+			flags.out.line(goal)
+		}
+
+		return nil
+	}
+
+	printGoals(os.Stdout, goals)
+
+	return nil
+}
+
+// prepareSearch runs the common part of the commands looking for the slots without booking.
+func prepareSearch(ctx context.Context, flags globalFlags) (search, error) {
+	conf, loc, err := loadConfig(flags, true)
+	if err != nil {
+		return search{}, err
+	}
+
+	client, err := connect(ctx, flags, conf, nil)
+	if err != nil {
+		return search{}, err
+	}
+
+	goals, err := selectGoals(ctx, client, conf, flags)
+	if err != nil {
+		return search{}, err
+	}
+
+	return newSearch(ctx, client, conf, loc, goals, flags, false)
+}
+
+func runSlots(ctx context.Context, flags globalFlags, _ []string) error {
+	found, err := prepareSearch(ctx, flags)
+	if err != nil {
+		return err
+	}
+
+	found.worker.printSlots(ctx, found.params)
+
+	return nil
+}
+
+func runPlan(ctx context.Context, flags globalFlags, _ []string) error {
+	found, err := prepareSearch(ctx, flags)
+	if err != nil {
+		return err
+	}
+
+	found.worker.printPlan(ctx, found.params)
+
+	return nil
+}
+
+// runBook books the slot of the goal starting at the time, a date-time or a moment like "tomorrow 19:00".
+// The ranges and the blackouts are not applied, the goal settings are.
+func runBook(ctx context.Context, flags globalFlags, args []string) error {
+	if len(args) != 2 { //nolint:mnd // the goal and the time.
+		return fmt.Errorf("%w: book <goal> <time>", ErrBadArgs)
+	}
+
+	conf, loc, err := loadConfig(flags, false)
+	if err != nil {
+		return err
+	}
+
+	moment, err := timeranges.ParseMoment(args[1], loc)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrBadArgs, err)
+	}
+
+	start := moment.At(time.Now())
+
+	var bot domain.Notificator
+	if conf.Bot != nil {
+		bot = tgbot.NewBot(conf.Bot.Token, conf.Bot.ChatID)
+	}
+
+	client, err := connect(ctx, flags, conf, bot)
+	if err != nil {
+		return err
+	}
+
+	flags.goals = args[0]
+
+	goals, err := selectGoals(ctx, client, conf, flags)
+	if err != nil {
+		return err
+	}
+
+	if len(goals) != 1 {
+		return fmt.Errorf("%w: %q matches %d goals", ErrBadArgs, args[0], len(goals))
+	}
+
+	found, err := newSearch(ctx, client, conf, loc, goals, flags, false)
+	if err != nil {
+		return err
+	}
+
+	if len(found.worker.prepareGoals(ctx, found.params, found.summaries)) < 1 {
+		return fmt.Errorf("goal %d: %w", goals[0].GoalID, ErrNothingToCheck)
+	}
+
+	params := found.params[0]
+	params.Ranges = [][2]time.Time{{start, start.Add(bookSearchSpan)}}
+	params.RangeRules = nil
+
+	candidates, err := client.GetCandidates(ctx, params)
+	if err != nil {
+		return fmt.Errorf("get candidates: %w", err)
+	}
+
+	for _, slot := range candidates.Slots {
+		if !slot.Start.Equal(start) {
+			continue
+		}
+
+		booking, err := client.BookSlot(ctx, candidates, slot)
+		if err != nil {
+			return fmt.Errorf("book: %w", err)
+		}
+
+		found.worker.export(booking)
+		client.Flush()
+
+		if flags.out.json {
+			flags.out.line(booking)
+		} else {
+			fmt.Println(booking.String())
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", ErrSlotNotFound, start.Format(appDateTimeLocale))
+}
+
+func runBookings(_ context.Context, flags globalFlags, _ []string) error {
+	conf, _, err := loadConfig(flags, true)
+	if err != nil {
+		return err
+	}
+
+	if conf.Export == nil || conf.Export.File == "" {
+		return ErrExportOff
+	}
+
+	feed, err := calfeed.OpenFeed(conf.Export.File)
+	if err != nil {
+		return fmt.Errorf("open feed: %w", err)
+	}
+
+	now := time.Now()
+
+	for _, booking := range feed.Bookings() {
+		if flags.out.json {
+			flags.out.line(booking)
+
+			continue
+		}
+
+		state := ""
+		if booking.End().Before(now) {
+			state = " (past)"
+		}
+
+		fmt.Printf("%7v - %-25s - %s%s\n", booking.Goal.GoalID, booking.Goal.Name, formatBooking(booking), state)
+	}
+
+	return nil
+}
+
+func runProfile(ctx context.Context, flags globalFlags, _ []string) error {
+	conf, _, err := loadConfig(flags, false)
+	if err != nil {
+		return err
+	}
+
+	client, err := connect(ctx, flags, conf, nil)
+	if err != nil {
+		return err
+	}
+
+	profile, err := client.GetProfile(ctx)
+	if err != nil {
+		return fmt.Errorf("get profile: %w", err)
+	}
+
+	if flags.out.json {
+		flags.out.line(profile)
+
+		return nil
+	}
+
+	fmt.Printf("login: %s\nemail: %s\nschool: %s\nwave: %s (%s)\nlevel: %d, xp: %d\n"+
+		"coins: %d, cookies: %d, review points: %d\n",
+		profile.Login, profile.Email, profile.School, profile.Wave, profile.EduForm, profile.Level,
+		profile.Experience, profile.Coins, profile.Cookies, profile.CodeReviewPoints)
+
+	return nil
+}
+
+func runEvaluations(ctx context.Context, flags globalFlags, _ []string) error {
+	conf, _, err := loadConfig(flags, false)
+	if err != nil {
+		return err
+	}
+
+	client, err := connect(ctx, flags, conf, nil)
+	if err != nil {
+		return err
+	}
+
+	goals, err := selectGoals(ctx, client, conf, flags)
+	if err != nil {
+		return err
+	}
+
+	for _, goal := range goals {
+		taskID, _, err := client.GetTaskIDAnswerID(ctx, goal.GoalID)
+		if err != nil {
+			return fmt.Errorf("goal %d: get task id: %w", goal.GoalID, err)
+		}
+
+		progress, err := client.GetReviewsProgress(ctx, goal.GoalID, taskID)
+		if err != nil {
+			return fmt.Errorf("goal %d: get reviews progress: %w", goal.GoalID, err)
+		}
+
+		if flags.out.json {
+			flags.out.line(evaluationsLine{
+				Goal:        goal,
+				Required:    progress.Required,
+				Relevant:    progress.Relevant,
+				ToSchedule:  progress.Unscheduled(),
+				Evaluations: progress.Evaluations.P2P,
+			})
+
+			continue
+		}
+
+		fmt.Printf("%7v - %-25s - required: %d, relevant: %d, to schedule: %d\n", goal.GoalID, goal.Name,
+			progress.Required, progress.Relevant, progress.Unscheduled())
+
+		for _, p2p := range progress.Evaluations.P2P {
+			fmt.Printf("\t- %s", p2p.Status)
+
+			if p2p.Reviewer != "" {
+				fmt.Printf(" by %s", p2p.Reviewer)
+			}
+
+			if p2p.StartTime != nil {
+				fmt.Printf(" at %s", p2p.StartTime.Local().Format(appDateTimeLocale))
+			}
+
+			fmt.Println()
+		}
+	}
+
+	return nil
+}
+
+// runDoctor runs all the checks and reports each, the error tells whether any failed.
+func runDoctor(ctx context.Context, flags globalFlags, _ []string) error {
+	failed := false
+
+	check := func(name string, err error) bool {
+		if flags.out.json {
+			line := checkLine{Check: name, OK: err == nil, Error: ""}
+			if err != nil {
+				line.Error = err.Error()
+			}
+
+			flags.out.line(line)
+		}
+
+		if err != nil {
+			failed = true
+
+			if !flags.out.json {
+				fmt.Printf("[FAIL] %s: %s\n", name, err)
+			}
+
+			return false
+		}
+
+		if !flags.out.json {
+			fmt.Printf("[ ok ] %s\n", name)
+		}
+
+		return true
+	}
+
+	conf, loc, err := loadConfig(flags, true)
+	if check("config", err) {
+		schedule, err := conf.schedule(loc)
+		if check("ranges, blackouts and calendars", err) {
+			windows, _ := schedule.Ranges.Windows(time.Now())
+			if len(windows) < 1 && !conf.Goals.hasRanges() {
+				check("ranges within the horizon", ErrNoRanges)
+			} else {
+				check(fmt.Sprintf("ranges within the horizon: %d", len(windows)), nil)
+			}
+		}
+
+		if conf.Export != nil {
+			_, err = calfeed.OpenFeed(conf.Export.File)
+			check("export file", err)
+		}
+
+		if conf.Bot != nil {
+			err = tgbot.NewBot(conf.Bot.Token, conf.Bot.ChatID).SendMessage(ctx, "schoolsubscriber doctor check")
+			check("bot", err)
+		}
+	}
+
+	client, err := connect(ctx, flags, conf, nil)
+	if check("login", err) {
+		goals, err := client.GetCurrentGoals(ctx)
+		if check("current goals", err) {
+			check(fmt.Sprintf("goals in evaluation: %d", len(domain.GoalsFilterEvaluated(goals))), nil)
+		}
+	}
+
+	if failed {
+		return ErrDoctor
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/eldarbr/schoolsubscriber/internal/ical"
+	"github.com/eldarbr/schoolsubscriber/internal/timeranges"
+)
+
+const (
+	// defaultHorizon is how far ahead the recurring ranges are searched.
+	defaultHorizon = 7 * 24 * time.Hour
+	// blackoutsMargin extends the blackouts past the horizon to cover the reviews starting at its end.
+	blackoutsMargin = 24 * time.Hour
+)
+
+var (
+	ErrFileFormatRanges = errors.New("ranges config has wrong format")
+	ErrFileFormatBuffer = errors.New("buffer config has wrong format")
+	ErrFileFormatMode   = errors.New("mode config has wrong format")
+)
+
+// confTimeRanges is either a start-end pair or a weekly rule like "Mon-Fri 18:00-23:00". The start
+// and the end are date-times or moments relative to the poll time, see timeranges.ParseMoment.
+type confTimeRanges struct {
+	Start  string `yaml:"start"`
+	End    string `yaml:"end"`
+	Weekly string `yaml:"weekly"`
+}
+
+type BotSetting struct {
+	Token  string `yaml:"token"`
+	ChatID int64  `yaml:"chat_id"`
+}
+
+// confExport writes the booked reviews to an iCalendar file, Listen optionally serves it over HTTP.
+type confExport struct {
+	File   string `yaml:"file"`
+	Listen string `yaml:"listen"`
+}
+
+// confEduContext overrides the ids of the campus, otherwise they are fetched from the platform.
+type confEduContext struct {
+	SchoolID  string `yaml:"school_id"`
+	ProductID string `yaml:"product_id"`
+	OrgUnitID string `yaml:"org_unit_id"`
+}
+
+type appConf struct {
+	TimeRanges []confTimeRanges `yaml:"ranges"`
+	Blackouts  []confTimeRanges `yaml:"blackouts"` // filtered out of the slots found, not cut out of the ranges.
+	Calendars  []string         `yaml:"calendars"`
+	Timezone   string           `yaml:"timezone"`
+	Horizon    time.Duration    `yaml:"horizon"`
+	MinLead    time.Duration    `yaml:"min_lead"`
+	Buffer     time.Duration    `yaml:"buffer"` // the minimal gap between the reviews of all the goals.
+	Mode       string           `yaml:"mode"`   // see domain.ParseBookingMode.
+	StaffSlots bool             `yaml:"staff_slots"`
+	Polling    *confPolling     `yaml:"polling"`
+	Bot        *BotSetting      `yaml:"bot"`
+	Select     []string         `yaml:"select"`
+	Ranking    *confRanking     `yaml:"ranking"`
+	Goals      confGoals        `yaml:"goals"`
+	Export     *confExport      `yaml:"export"`
+	Planner    bool             `yaml:"planner"` // plan the slots of all the goals together.
+	EduContext *confEduContext  `yaml:"edu_context"`
+}
+
+// location returns the configured time zone, the system one by default.
+func (conf *appConf) location() (*time.Location, error) {
+	if conf.Timezone == "" {
+		return time.Local, nil
+	}
+
+	loc, err := time.LoadLocation(conf.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("load timezone: %w", err)
+	}
+
+	return loc, nil
+}
+
+func (conf *appConf) schedule(loc *time.Location) (searchSchedule, error) {
+	rules, err := convConfTimeRanges(conf.TimeRanges, loc)
+	if err != nil {
+		return searchSchedule{}, err
+	}
+
+	blackouts, err := convConfTimeRanges(conf.Blackouts, loc)
+	if err != nil {
+		return searchSchedule{}, fmt.Errorf("blackouts: %w", err)
+	}
+
+	for _, path := range conf.Calendars {
+		calendar, err := ical.OpenFile(path, loc)
+		if err != nil {
+			return searchSchedule{}, fmt.Errorf("calendars: %w", err)
+		}
+
+		blackouts = append(blackouts, calendar)
+	}
+
+	horizon := conf.Horizon
+	if horizon <= 0 {
+		horizon = defaultHorizon
+	} else {
+		warnPastHorizon(rules, horizon, "the")
+	}
+
+	return searchSchedule{
+		// the default horizon only bounds the recurring ranges, a set one bounds all of them.
+		Ranges: timeranges.Schedule{Rules: rules, MinLead: conf.MinLead, Horizon: horizon, ClipOneOff: conf.Horizon > 0},
+		Blackouts: timeranges.Schedule{
+			Rules: blackouts, MinLead: 0, Horizon: horizon + blackoutsMargin, ClipOneOff: true,
+		},
+	}, nil
+}
+
+// warnPastHorizon logs the ranges lying entirely past the horizon, they are never searched.
+func warnPastHorizon(rules []timeranges.Rule, horizon time.Duration, owner string) {
+	now := time.Now()
+	end := now.Add(horizon)
+
+	for i, rule := range rules {
+		windows := rule.Windows(now, now, end)
+		if len(windows) > 0 && !slices.ContainsFunc(windows, func(w [2]time.Time) bool { return w[0].Before(end) }) {
+			log.Printf("Warn %s range %d lies past the horizon of %s, it is not searched\n", owner, i+1, horizon)
+		}
+	}
+}
+
+func convConfTimeRanges(ranges []confTimeRanges, loc *time.Location) ([]timeranges.Rule, error) {
+	result := make([]timeranges.Rule, 0, len(ranges))
+
+	for _, r := range ranges {
+		if r.Weekly != "" {
+			weekly, err := timeranges.ParseWeekly(r.Weekly, loc)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrFileFormatRanges, err)
+			}
+
+			result = append(result, weekly)
+
+			continue
+		}
+
+		if r.Start == "" || r.End == "" {
+			continue
+		}
+
+		start, err := timeranges.ParseMoment(r.Start, loc)
+		if err != nil {
+			return nil, fmt.Errorf("%w: start: %w", ErrFileFormatRanges, err)
+		}
+
+		end, err := timeranges.ParseMoment(r.End, loc)
+		if err != nil {
+			return nil, fmt.Errorf("%w: end: %w", ErrFileFormatRanges, err)
+		}
+
+		result = append(result, timeranges.Relative{Start: start, End: end})
+	}
+
+	return result, nil
+}
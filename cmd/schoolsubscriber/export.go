@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/eldarbr/schoolsubscriber/internal/calfeed"
+)
+
+const (
+	exportReadHeaderTimeout = 5 * time.Second
+	exportShutdownTimeout   = 5 * time.Second
+)
+
+var ErrExportNoFile = errors.New("export requires a file")
+
+// openExport opens the calendar feed and, with serve, starts serving it if configured. Only the long
+// running watch serves the feed, the one-shot commands would compete for the port. It returns nil
+// when the export is off, the server stops with the context.
+func openExport(ctx context.Context, conf *confExport, serve bool) (*calfeed.Feed, error) {
+	if conf == nil {
+		return nil, nil //nolint:nilnil // the export is optional.
+	}
+
+	if conf.File == "" {
+		return nil, ErrExportNoFile
+	}
+
+	feed, err := calfeed.OpenFeed(conf.File)
+	if err != nil {
+		return nil, fmt.Errorf("open feed: %w", err)
+	}
+
+	if conf.Listen == "" || !serve {
+		return feed, nil
+	}
+
+	server := &http.Server{
+		Addr:              conf.Listen,
+		Handler:           feed,
+		ReadHeaderTimeout: exportReadHeaderTimeout,
+	}
+
+	go func() {
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Println("Err Serve calendar feed:", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), exportShutdownTimeout)
+		defer cancel()
+
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	log.Println("serving the calendar feed on", conf.Listen)
+
+	return feed, nil
+}
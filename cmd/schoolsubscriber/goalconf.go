@@ -0,0 +1,352 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eldarbr/schoolsubscriber/internal/client/tgbot"
+	"github.com/eldarbr/schoolsubscriber/internal/domain"
+	"github.com/eldarbr/schoolsubscriber/internal/timeranges"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	rankingOrderEarliest = "earliest"
+	rankingOrderLatest   = "latest"
+)
+
+var (
+	ErrFileFormatGoals   = errors.New("goals config has wrong format")
+	ErrFileFormatRanking = errors.New("ranking config has wrong format")
+)
+
+// confRanking configures the slot preference, see domain.SlotRanking.
+type confRanking struct {
+	Order         string             `yaml:"order"`
+	TimeOfDay     confTimeOfDay      `yaml:"time_of_day"`
+	RangePriority bool               `yaml:"range_priority"`
+	Weekdays      map[string]float64 `yaml:"weekdays"`
+}
+
+// confTimeOfDay is either a time like "19:00" for all the days or a mapping of the days, as in
+// the weekly ranges, to the times, like {"Mon-Fri": "19:00", "Sat,Sun": "10:00"}. The later
+// entries win for the days listed several times.
+type confTimeOfDay struct {
+	entries [][2]string // days and time.
+}
+
+func (t *confTimeOfDay) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind { //nolint:exhaustive // the rest is invalid.
+	case yaml.ScalarNode:
+		t.entries = [][2]string{{"daily", node.Value}}
+	case yaml.MappingNode:
+		t.entries = make([][2]string, 0, len(node.Content)/2) //nolint:mnd // key-value pairs.
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			t.entries = append(t.entries, [2]string{node.Content[i].Value, node.Content[i+1].Value})
+		}
+	default:
+		return fmt.Errorf("%w: time_of_day should be a time or a mapping", ErrFileFormatRanking)
+	}
+
+	return nil
+}
+
+// preferred returns the preferred time per weekday, nil if not configured.
+func (t confTimeOfDay) preferred() (map[time.Weekday]time.Duration, error) {
+	if len(t.entries) < 1 {
+		return nil, nil //nolint:nilnil // not configured.
+	}
+
+	result := make(map[time.Weekday]time.Duration, len(t.entries))
+
+	for _, entry := range t.entries {
+		days, err := timeranges.ParseDays(entry[0])
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", entry[0], err)
+		}
+
+		clock, err := timeranges.ParseClock(entry[1])
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", entry[0], err)
+		}
+
+		for day, ok := range days {
+			if ok {
+				result[time.Weekday(day)] = clock
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// confGoal overrides the common settings for the goals matched by its key.
+type confGoal struct {
+	Ranking     *confRanking     `yaml:"ranking"`
+	Priority    int              `yaml:"priority"` // the planner serves the higher priority first.
+	TimeRanges  []confTimeRanges `yaml:"ranges"`
+	Mode        string           `yaml:"mode"`
+	StaffSlots  *bool            `yaml:"staff_slots"`
+	MaxBookings int              `yaml:"max_bookings"`
+	Bot         *BotSetting      `yaml:"bot"` // the token defaults to the common bot one.
+}
+
+type confGoalEntry struct {
+	Selector string
+	Conf     confGoal
+}
+
+// confGoals keeps the goal blocks in the file order, the first block matching a goal applies.
+type confGoals []confGoalEntry
+
+func (g *confGoals) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("%w: goals should be a mapping", ErrFileFormatGoals)
+	}
+
+	result := make(confGoals, 0, len(node.Content)/2) //nolint:mnd // key-value pairs.
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		var conf confGoal
+
+		err := node.Content[i+1].Decode(&conf)
+		if err != nil {
+			return fmt.Errorf("%w: goal %q: %w", ErrFileFormatGoals, node.Content[i].Value, err)
+		}
+
+		result = append(result, confGoalEntry{Selector: node.Content[i].Value, Conf: conf})
+	}
+
+	*g = result
+
+	return nil
+}
+
+// lookup returns the first goal block matching the goal.
+func (g confGoals) lookup(goal domain.Goal) (confGoal, bool) {
+	for _, entry := range g {
+		ok, err := domain.GoalMatches(goal, entry.Selector)
+		if err == nil && ok {
+			return entry.Conf, true
+		}
+	}
+
+	return confGoal{}, false
+}
+
+// hasRanges reports whether any goal block has its own ranges.
+func (g confGoals) hasRanges() bool {
+	for _, entry := range g {
+		if len(entry.Conf.TimeRanges) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validate checks the goal keys and blocks at the start rather than when a goal is reached.
+func (g confGoals) validate() error {
+	for _, entry := range g {
+		_, err := domain.GoalMatches(domain.Goal{}, entry.Selector)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrFileFormatGoals, err)
+		}
+
+		_, err = entry.Conf.Ranking.toSlotRanking(time.Local)
+		if err != nil {
+			return fmt.Errorf("goal %q: %w", entry.Selector, err)
+		}
+
+		_, err = convConfTimeRanges(entry.Conf.TimeRanges, time.Local)
+		if err != nil {
+			return fmt.Errorf("goal %q: %w", entry.Selector, err)
+		}
+
+		_, err = parseMode(entry.Conf.Mode)
+		if err != nil {
+			return fmt.Errorf("goal %q: %w", entry.Selector, err)
+		}
+
+		if entry.Conf.MaxBookings < 0 {
+			return fmt.Errorf("%w: goal %q: negative max_bookings", ErrFileFormatGoals, entry.Selector)
+		}
+	}
+
+	return nil
+}
+
+func (conf *appConf) validate() error {
+	for _, entry := range conf.Goals {
+		if entry.Conf.Bot != nil && entry.Conf.Bot.Token == "" && conf.Bot == nil {
+			return fmt.Errorf("%w: goal %q: bot token is missing", ErrFileFormatGoals, entry.Selector)
+		}
+	}
+
+	if conf.Buffer < 0 {
+		return fmt.Errorf("%w: negative duration", ErrFileFormatBuffer)
+	}
+
+	_, err := conf.Ranking.toSlotRanking(time.Local)
+	if err != nil {
+		return err
+	}
+
+	_, err = parseMode(conf.Mode)
+	if err != nil {
+		return err
+	}
+
+	_, err = conf.Polling.poller(time.Local)
+	if err != nil {
+		return err
+	}
+
+	return conf.Goals.validate()
+}
+
+// goalParams builds the search parameters of the goal from its block and the common settings.
+func (conf *appConf) goalParams(goal domain.Goal, loc *time.Location) (domain.SubscribeParams, error) {
+	ranking, err := conf.goalRanking(goal, loc)
+	if err != nil {
+		return domain.SubscribeParams{}, err
+	}
+
+	goalConf, _ := conf.Goals.lookup(goal)
+
+	modeName := conf.Mode
+	if goalConf.Mode != "" {
+		modeName = goalConf.Mode
+	}
+
+	mode, err := parseMode(modeName)
+	if err != nil {
+		return domain.SubscribeParams{}, err
+	}
+
+	staff := conf.StaffSlots
+	if goalConf.StaffSlots != nil {
+		staff = *goalConf.StaffSlots
+	}
+
+	return domain.SubscribeParams{
+		Goal:        goal,
+		Mode:        mode,
+		Staff:       staff,
+		Ranking:     ranking,
+		Priority:    goalConf.Priority,
+		MaxBookings: goalConf.MaxBookings,
+		Notificator: conf.goalBot(goalConf),
+	}, nil
+}
+
+// parseMode parses the booking mode, online by default.
+func parseMode(name string) (domain.BookingMode, error) {
+	if name == "" {
+		return domain.ModeOnline, nil
+	}
+
+	mode, err := domain.ParseBookingMode(name)
+	if err != nil {
+		return mode, fmt.Errorf("%w: %w", ErrFileFormatMode, err)
+	}
+
+	return mode, nil
+}
+
+// goalBot returns the bot of the goal block, nil to use the common one.
+func (conf *appConf) goalBot(goalConf confGoal) domain.Notificator {
+	if goalConf.Bot == nil {
+		return nil
+	}
+
+	token := goalConf.Bot.Token
+	if token == "" {
+		token = conf.Bot.Token
+	}
+
+	return tgbot.NewBot(token, goalConf.Bot.ChatID)
+}
+
+// goalSchedule replaces the common ranges with the ones of the goal block, if any.
+func (conf *appConf) goalSchedule(goal domain.Goal, loc *time.Location, common searchSchedule,
+) (searchSchedule, error) {
+	goalConf, ok := conf.Goals.lookup(goal)
+	if !ok || len(goalConf.TimeRanges) < 1 {
+		return common, nil
+	}
+
+	rules, err := convConfTimeRanges(goalConf.TimeRanges, loc)
+	if err != nil {
+		return common, err
+	}
+
+	if conf.Horizon > 0 {
+		warnPastHorizon(rules, conf.Horizon, fmt.Sprintf("goal %d", goal.GoalID))
+	}
+
+	result := common
+	result.Ranges.Rules = rules
+
+	return result, nil
+}
+
+// goalRanking picks the ranking of the goal block, or the common one.
+func (conf *appConf) goalRanking(goal domain.Goal, loc *time.Location) (domain.SlotRanking, error) {
+	ranking := conf.Ranking
+
+	if goalConf, ok := conf.Goals.lookup(goal); ok && goalConf.Ranking != nil {
+		ranking = goalConf.Ranking
+	}
+
+	return ranking.toSlotRanking(loc)
+}
+
+func (r *confRanking) toSlotRanking(loc *time.Location) (domain.SlotRanking, error) {
+	result := domain.SlotRanking{}
+
+	if r == nil {
+		return result, nil
+	}
+
+	switch strings.ToLower(r.Order) {
+	case "", rankingOrderEarliest:
+	case rankingOrderLatest:
+		result.Latest = true
+	default:
+		return result, fmt.Errorf("%w: unknown order %q", ErrFileFormatRanking, r.Order)
+	}
+
+	if r.RangePriority {
+		result.Scorers = append(result.Scorers, domain.RangePriorityScorer{})
+	}
+
+	preferred, err := r.TimeOfDay.preferred()
+	if err != nil {
+		return result, fmt.Errorf("%w: time_of_day: %w", ErrFileFormatRanking, err)
+	}
+
+	if preferred != nil {
+		result.Scorers = append(result.Scorers, domain.TimeOfDayScorer{Preferred: preferred, Location: loc})
+	}
+
+	if len(r.Weekdays) > 0 {
+		weights := make(map[time.Weekday]float64, len(r.Weekdays))
+
+		for name, weight := range r.Weekdays {
+			day, err := timeranges.ParseWeekday(name)
+			if err != nil {
+				return result, fmt.Errorf("%w: weekdays: %w", ErrFileFormatRanking, err)
+			}
+
+			weights[day] = weight
+		}
+
+		result.Scorers = append(result.Scorers, domain.WeekdayScorer{Weights: weights, Location: loc})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/eldarbr/schoolsubscriber/internal/domain"
+)
+
+var (
+	ErrNoGoalsSelected    = errors.New("the selection matched no goals")
+	ErrGoalsNotSpecified  = errors.New("several goals are in evaluation, choose them with -g or the select config key")
+	ErrInteractiveAborted = errors.New("interactive goal selection aborted")
+)
+
+// chooseGoals applies the selectors when there are any, and only falls back to
+// the interactive prompt if stdin is a terminal.
+func chooseGoals(ctx context.Context, goals []domain.Goal, selectors []string) ([]domain.Goal, error) {
+	if len(selectors) > 0 {
+		chosen, err := domain.GoalsSelect(goals, selectors)
+		if err != nil {
+			return nil, fmt.Errorf("select goals: %w", err)
+		}
+
+		if len(chosen) < 1 {
+			return nil, ErrNoGoalsSelected
+		}
+
+		log.Println("goals have been chosen by the selection:")
+		printGoals(log.Writer(), chosen)
+
+		return chosen, nil
+	}
+
+	if len(goals) == 1 {
+		log.Println("a goal has been chosen automatically:")
+		printGoals(log.Writer(), goals)
+
+		return []domain.Goal{goals[0]}, nil
+	}
+
+	if !stdinIsTerminal() {
+		return nil, ErrGoalsNotSpecified
+	}
+
+	return interactiveGoalDecision(ctx, goals)
+}
+
+func interactiveGoalDecision(ctx context.Context, goals []domain.Goal) ([]domain.Goal, error) {
+	if len(goals) < 1 {
+		return []domain.Goal{}, nil
+	}
+
+	lines := readLines(os.Stdin)
+
+	for {
+		printGoals(os.Stdout, goals)
+
+		fmt.Print("Choose goals - comma separated ids, name globs or \"all\", prefix with ! to exclude: ")
+
+		var (
+			input string
+			ok    bool
+		)
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: %w", ErrInteractiveAborted, ctx.Err())
+		case input, ok = <-lines:
+			if !ok {
+				return nil, fmt.Errorf("%w: stdin closed", ErrInteractiveAborted)
+			}
+		}
+
+		selectors := domain.SplitSelectors(input)
+		if len(selectors) < 1 {
+			continue
+		}
+
+		result, err := domain.GoalsSelect(goals, selectors)
+		if err != nil {
+			log.Println("Err given selection is not valid:", err)
+
+			continue
+		}
+
+		if len(result) < 1 {
+			log.Println("Err given selection matched no goals")
+
+			continue
+		}
+
+		return result, nil
+	}
+}
+
+// readLines feeds stdin lines to the channel, so that reading might be interrupted.
+func readLines(file *os.File) <-chan string {
+	lines := make(chan string)
+
+	go func() {
+		defer close(lines)
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	return lines
+}
+
+func printGoals(writer io.Writer, goals []domain.Goal) {
+	for i := range goals {
+		fmt.Fprintf(writer, "%7v - %-25s - %s\n", goals[i].GoalID, goals[i].Name, goals[i].Status)
+	}
+}
+
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/eldarbr/schoolsubscriber/internal/domain"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+
+	eventStarted = "started"
+	eventAlive   = "alive"
+	eventBooked  = "booked"
+	eventCovered = "covered"
+	eventLimited = "limited"
+	eventError   = "error"
+	eventStopped = "stopped"
+	eventSummary = "summary"
+)
+
+var ErrBadOutput = errors.New("unknown output format")
+
+// output writes the results of the commands. In the json mode every value is a single line on
+// stdout, the logs stay on stderr.
+type output struct {
+	json    bool
+	stream  bool // the watch events are streamed.
+	mutex   sync.Mutex
+	encoder *json.Encoder
+}
+
+func newOutput(format string) (*output, error) {
+	switch format {
+	case "", outputText:
+		return &output{json: false, encoder: nil}, nil
+	case outputJSON:
+		return &output{json: true, encoder: json.NewEncoder(os.Stdout)}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrBadOutput, format)
+	}
+}
+
+// line writes the value as one JSON line.
+func (o *output) line(value any) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	err := o.encoder.Encode(value)
+	if err != nil {
+		log.Println("Err Encode output:", err)
+	}
+}
+
+// slotLine is a line of the slots command.
+type slotLine struct {
+	Goal  domain.Goal `json:"goal"`
+	Slot  domain.Slot `json:"slot"`
+	Score float64     `json:"score"`
+}
+
+// evaluationsLine is a line of the evaluations command.
+type evaluationsLine struct {
+	Goal        domain.Goal            `json:"goal"`
+	Required    int                    `json:"required"`
+	Relevant    int                    `json:"relevant"`
+	ToSchedule  int                    `json:"to_schedule"`
+	Evaluations []domain.P2PEvaluation `json:"evaluations"`
+}
+
+// checkLine is a line of the doctor command.
+type checkLine struct {
+	Check string `json:"check"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// watchEvent is a line of the watch command stream.
+type watchEvent struct {
+	Time    time.Time       `json:"time"`
+	Event   string          `json:"event"`
+	Goal    *domain.Goal    `json:"goal,omitempty"`
+	Booking *domain.Booking `json:"booking,omitempty"`
+	Summary *goalSummary    `json:"summary,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// event streams a watch event in the json mode, the text mode relies on the logs.
+func (o *output) event(kind string, goal *domain.Goal, err error) {
+	if !o.json || !o.stream {
+		return
+	}
+
+	event := watchEvent{Time: time.Now(), Event: kind, Goal: goal}
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	o.line(event)
+}
+
+func (o *output) booked(booking domain.Booking) {
+	if !o.json || !o.stream {
+		return
+	}
+
+	o.line(watchEvent{Time: time.Now(), Event: eventBooked, Goal: &booking.Goal, Booking: &booking})
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/eldarbr/schoolsubscriber/internal/domain"
+)
+
+// prepareGoals looks up the task ids, it returns the indices of the goals ready for the search.
+func (w *watcher) prepareGoals(ctx context.Context, params []domain.SubscribeParams, summaries []goalSummary,
+) []int {
+	ready := make([]int, 0, len(params))
+
+	for i := range params {
+		taskID, _, err := w.client.GetTaskIDAnswerID(ctx, params[i].Goal.GoalID)
+		if err != nil {
+			log.Println("-", params[i].Goal.GoalID, "Err Get task and answer ids: ", err)
+			w.out.event(eventError, &params[i].Goal, err)
+
+			summaries[i].Errors++
+
+			continue
+		}
+
+		params[i].TaskID = taskID
+		ready = append(ready, i)
+	}
+
+	return ready
+}
+
+// candidates collects the slots of the ready goals not covered yet. The errors are logged,
+// they are also returned joined.
+func (w *watcher) candidates(ctx context.Context, params []domain.SubscribeParams, ready []int,
+	summaries []goalSummary,
+) ([]domain.GoalCandidates, error) {
+	now := time.Now()
+	result := make([]domain.GoalCandidates, 0, len(ready))
+
+	var errs []error
+
+	for _, i := range ready {
+		if summaries[i].Covered || ctx.Err() != nil {
+			continue
+		}
+
+		summaries[i].Attempts++
+
+		w.scheduleFor(params[i].Goal.GoalID).fill(&params[i], now)
+
+		candidates, err := w.client.GetCandidates(ctx, params[i])
+		if w.checkLimited(&summaries[i], err) {
+			continue
+		}
+		if errors.Is(err, domain.ErrFullyScheduled) {
+			log.Println("-", params[i].Goal.GoalID, "all the required reviews are scheduled, waiting for a cancellation")
+			w.out.event(eventCovered, &params[i].Goal, nil)
+
+			summaries[i].Covered = true
+
+			continue
+		}
+
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Println("-", params[i].Goal.GoalID, "Err Candidates:", err)
+				w.out.event(eventError, &params[i].Goal, err)
+
+				summaries[i].Errors++
+				errs = append(errs, err)
+			}
+
+			continue
+		}
+
+		if len(candidates.Slots) > 0 {
+			result = append(result, candidates)
+		}
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// plannerWorker polls the slots of all the goals at once and books them by the plan.
+func (w *watcher) plannerWorker(ctx context.Context, params []domain.SubscribeParams, summaries []goalSummary) {
+	ready := w.prepareGoals(ctx, params, summaries)
+	if len(ready) < 1 {
+		return
+	}
+
+	log.Println("planner alive")
+	w.out.event(eventStarted, nil, nil)
+
+	aliveTicker := time.NewTicker(aliveProbePeriod)
+	defer aliveTicker.Stop()
+
+	planPoller := w.poll
+
+	attemptTimer := time.NewTimer(planPoller.Interval)
+	defer attemptTimer.Stop()
+
+	coveredTicker := time.NewTicker(coveredCheckPeriod)
+	defer coveredTicker.Stop()
+
+	byGoal := make(map[int]int, len(params))
+	for i := range params {
+		byGoal[params[i].Goal.GoalID] = i
+	}
+
+	attempt := func() error {
+		found, err := w.candidates(ctx, params, ready, summaries)
+
+		plan := w.client.Plan(found)
+		if len(plan) < 1 {
+			return err
+		}
+
+		bookings, bookErr := w.client.BookPlan(ctx, plan)
+		if bookErr != nil && ctx.Err() == nil {
+			log.Println("Err Book plan:", bookErr)
+			w.out.event(eventError, nil, bookErr)
+		}
+
+		for _, booking := range bookings {
+			w.record(&summaries[byGoal[booking.Goal.GoalID]], booking)
+		}
+
+		return errors.Join(err, bookErr)
+	}
+
+	err := attempt() // initial tick
+
+	if w.once {
+		return
+	}
+
+	attemptTimer.Reset(w.nextPoll(&planPoller, "planner", err))
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("planner stopped")
+			w.out.event(eventStopped, nil, nil)
+
+			return
+		case <-aliveTicker.C:
+			log.Println("planner alive")
+			w.out.event(eventAlive, nil, nil)
+		case <-attemptTimer.C:
+			attemptTimer.Reset(w.nextPoll(&planPoller, "planner", attempt()))
+		case <-coveredTicker.C:
+			for _, i := range ready {
+				summaries[i].Covered = false // re-checked by the next attempt.
+			}
+		}
+	}
+}
+
+// printPlan searches the slots once and prints what would be booked.
+func (w *watcher) printPlan(ctx context.Context, params []domain.SubscribeParams) {
+	summaries := make([]goalSummary, len(params))
+	ready := w.prepareGoals(ctx, params, summaries)
+	found, _ := w.candidates(ctx, params, ready, summaries) // logged.
+	plan := w.client.Plan(found)
+
+	if w.out.json {
+		for _, planned := range plan {
+			w.out.line(planned)
+		}
+
+		return
+	}
+
+	for _, i := range ready {
+		if summaries[i].Covered {
+			fmt.Printf("%7v - %-25s - all the required reviews are scheduled\n",
+				params[i].Goal.GoalID, params[i].Goal.Name)
+		}
+	}
+
+	if len(plan) < 1 {
+		fmt.Println("Nothing to book")
+
+		return
+	}
+
+	fmt.Println("Plan:")
+
+	for _, planned := range plan {
+		fmt.Printf("%7v - %-25s - %s - %s (score %g)\n", planned.Goal.GoalID, planned.Goal.Name,
+			planned.Slot.Start.Local().Format(appDateTimeLocale),
+			planned.Slot.End().Local().Format(appDateTimeLocale), planned.Score)
+	}
+}
+
+// printSlots searches the slots once and prints the ones each goal might be booked into, best first.
+func (w *watcher) printSlots(ctx context.Context, params []domain.SubscribeParams) {
+	summaries := make([]goalSummary, len(params))
+	ready := w.prepareGoals(ctx, params, summaries)
+	found, _ := w.candidates(ctx, params, ready, summaries) // logged.
+
+	if w.out.json {
+		for _, candidates := range found {
+			for _, slot := range candidates.Slots {
+				w.out.line(slotLine{Goal: candidates.Params.Goal, Slot: slot, Score: candidates.Params.Ranking.Score(slot)})
+			}
+		}
+
+		return
+	}
+
+	for _, i := range ready {
+		fmt.Printf("%7v - %-25s", params[i].Goal.GoalID, params[i].Goal.Name)
+
+		switch {
+		case summaries[i].Covered:
+			fmt.Println(" - all the required reviews are scheduled")
+		case summaries[i].Limited:
+			fmt.Println(" - reached max_bookings")
+		default:
+			fmt.Println()
+		}
+
+		for _, candidates := range found {
+			if candidates.Params.Goal.GoalID != params[i].Goal.GoalID {
+				continue
+			}
+
+			for _, slot := range candidates.Slots {
+				kind := "peer"
+				if slot.Staff {
+					kind = "staff"
+				}
+
+				fmt.Printf("\t- %s - %s %s (score %g)\n", slot.Start.Local().Format(appDateTimeLocale),
+					slot.End().Local().Format(appDateTimeLocale), kind, candidates.Params.Ranking.Score(slot))
+			}
+		}
+	}
+}
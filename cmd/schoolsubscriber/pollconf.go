@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eldarbr/schoolsubscriber/internal/poller"
+	"github.com/eldarbr/schoolsubscriber/internal/timeranges"
+)
+
+const (
+	defaultPollJitter     = 0.2
+	defaultPollMaxBackoff = 5 * time.Minute
+)
+
+var ErrFileFormatPolling = errors.New("polling config has wrong format")
+
+// confPolling tunes how often the slots are polled.
+type confPolling struct {
+	Interval   time.Duration      `yaml:"interval"`
+	Jitter     *float64           `yaml:"jitter"`
+	MaxBackoff time.Duration      `yaml:"max_backoff"`
+	Periods    []confPollInterval `yaml:"periods"`
+}
+
+// confPollInterval overrides the interval within the weekly rule, e.g. "Mon-Fri 18:00-23:00".
+type confPollInterval struct {
+	Weekly   string        `yaml:"weekly"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+// poller builds the polling policy, the defaults apply to what is not configured.
+func (c *confPolling) poller(loc *time.Location) (poller.Poller, error) {
+	result := poller.Poller{
+		Interval:   slotsCheckPeriod,
+		Jitter:     defaultPollJitter,
+		MaxBackoff: defaultPollMaxBackoff,
+		Periods:    nil,
+	}
+
+	if c == nil {
+		return result, nil
+	}
+
+	if c.Interval > 0 {
+		result.Interval = c.Interval
+	}
+
+	if c.Jitter != nil {
+		if *c.Jitter < 0 || *c.Jitter >= 1 {
+			return result, fmt.Errorf("%w: jitter should be in [0, 1)", ErrFileFormatPolling)
+		}
+
+		result.Jitter = *c.Jitter
+	}
+
+	if c.MaxBackoff > 0 {
+		result.MaxBackoff = c.MaxBackoff
+	}
+
+	for _, period := range c.Periods {
+		if period.Interval <= 0 {
+			return result, fmt.Errorf("%w: %q: interval should be positive", ErrFileFormatPolling, period.Weekly)
+		}
+
+		weekly, err := timeranges.ParseWeekly(period.Weekly, loc)
+		if err != nil {
+			return result, fmt.Errorf("%w: %w", ErrFileFormatPolling, err)
+		}
+
+		result.Periods = append(result.Periods, poller.Period{Rule: weekly, Interval: period.Interval})
+	}
+
+	return result, nil
+}
@@ -1,285 +1,91 @@
 package main
 
 import (
-	"bufio"
 	"context"
-	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
-	"strconv"
-	"strings"
-	"sync"
+	"os/signal"
+	"syscall"
 	"time"
 
-	"github.com/eldarbr/go-auth/pkg/config"
-	"github.com/eldarbr/schoolauth"
-	"github.com/eldarbr/schoolsubscriber/internal/client/tgbot"
-	"github.com/eldarbr/schoolsubscriber/internal/domain"
+	"github.com/eldarbr/schoolsubscriber/internal/schoolgql"
 )
 
-type ConfTime time.Time
-
-type confTimeRanges struct {
-	Start *ConfTime `yaml:"start"`
-	End   *ConfTime `yaml:"end"`
-}
-
-type BotSetting struct {
-	Token  string `yaml:"token"`
-	ChatID int64  `yaml:"chat_id"`
-}
-
-type appConf struct {
-	TimeRanges []confTimeRanges `yaml:"ranges"`
-	Bot        *BotSetting      `yaml:"bot"`
-}
-
 const (
-	slotsCheckPeriod  = 6 * time.Second
-	aliveProbePeriod  = 30 * time.Minute
-	appDateTimeLocale = time.DateTime
+	slotsCheckPeriod    = 6 * time.Second
+	aliveProbePeriod    = 30 * time.Minute
+	coveredCheckPeriod  = 5 * time.Minute
+	finalMessageTimeout = 10 * time.Second
+	appDateTimeLocale   = time.DateTime
 )
 
-var ErrFileFormatRanges = errors.New("ranges file has wrong format")
-
 func main() {
-	var (
-		conf appConf
-
-		flgUsername = flag.String("u", "", "username")
-		flgPassword = flag.String("p", "", "password")
-		flgConf     = flag.String("c", "", "path to the config")
-	)
-
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		stop() // a second signal kills the process immediately.
+	}()
+
+	var flags globalFlags
+
+	flag.StringVar(&flags.username, "u", "", "username")
+	flag.StringVar(&flags.password, "p", "", "password")
+	flag.StringVar(&flags.conf, "c", "", "path to the config")
+	flag.StringVar(&flags.goals, "g", "",
+		"goals to subscribe for - comma separated ids, name globs or \"all\", prefix with ! to exclude")
+	flag.BoolVar(&flags.dryRun, "dry-run", false, "search once and report the slots that would be booked")
+	flag.StringVar(&flags.output, "o", outputText, "output format: text or json, one object per line")
+	flag.StringVar(&flags.endpoint, "endpoint", schoolgql.DefaultEndpoint, "GraphQL endpoint of the platform")
+	flag.StringVar(&flags.contextURL, "context-url", "",
+		"edu context info url, the one of the endpoint server by default")
+	flag.BoolVar(&flags.verbose, "v", false, "log every request to the platform")
+	flag.BoolVar(&flags.tokenCache, "token-cache", false,
+		"keep the token in $XDG_STATE_HOME/schoolsubscriber/token.json to reuse it after a restart")
+
+	flag.Usage = usage
 	flag.Parse()
 
-	if *flgUsername == "" || *flgPassword == "" || *flgConf == "" {
-		log.Println("Error - please provide username and password and path to a yaml with valid time ranges")
-
-		return
-	}
-
-	err := config.ParseConfig(*flgConf, &conf)
-	if err != nil {
-		log.Println("Err Reading config:", err)
-
-		return
-	}
-
-	timeRanges := convConfTimeRanges(conf.TimeRanges)
-	if len(timeRanges) < 1 {
-		log.Println("Err Parse time ranges: no ranges")
-
-		return
-	}
-
-	var bot domain.Notificator
-
-	if conf.Bot != nil {
-		bot = tgbot.NewBot(conf.Bot.Token, conf.Bot.ChatID)
-		err = bot.SendMessage(context.Background(), "Hi! Searching slots")
-		if err != nil {
-			log.Println("Err bot initialization message:", err)
-			bot = nil
-		}
-	}
-
-	managedToken := schoolauth.NewManagedToken(*flgUsername, *flgPassword, nil)
-
-	client, err := domain.NewDomain(context.Background(), managedToken, *flgUsername, bot)
-	if err != nil {
-		log.Println("Err New domain:", err)
-
-		return
-	}
-
-	goals, err := client.GetCurrentGoals(context.Background())
-	if err != nil {
-		log.Println("Err Get current goals:", err)
-
-		return
-	}
-
-	goals = domain.GoalsFilterEvaluated(goals)
-	if len(goals) < 1 {
-		log.Println("No goals to review :)")
-
-		return
-	}
-
-	chosenGoals := interactiveGoalDecision(goals)
-
-	PrintRanges(timeRanges)
-
-	group := sync.WaitGroup{}
-	for _, goal := range chosenGoals {
-		group.Add(1)
-
-		go attemptWorker(client, timeRanges, goal, &group)
-	}
-
-	group.Wait()
-}
-
-func attemptWorker(client *domain.Domain, timeRanges [][2]time.Time, goal domain.Goal, group *sync.WaitGroup) {
-	if group != nil {
-		defer group.Done()
-	}
-
-	taskID, answerID, err := client.GetTaskIDAnswerID(context.Background(), goal.GoalID)
+	out, err := newOutput(flags.output)
 	if err != nil {
-		log.Println("-", goal.GoalID, "Err Get task and answer ids: ", err)
-
-		return
-	}
-
-	log.Println("-", goal.GoalID, "alive")
-
-	aliveTicker := time.NewTicker(aliveProbePeriod)
-	defer aliveTicker.Stop()
-
-	attemptTicker := time.NewTicker(slotsCheckPeriod)
-	defer attemptTicker.Stop()
-
-	var (
-		succ   bool
-		succCh = make(chan bool, 1)
-		start  time.Time
-	)
-
-	succCh <- true // initial tick
-
-	attempt := func() {
-		start, succ, err = client.AttemptSubscribe(context.Background(), taskID, answerID, timeRanges, true)
-		if err != nil {
-			log.Println("-", goal.GoalID, "Err Attempt:", err)
-
-			return
-		}
-
-		if succ {
-			succCh <- succ // try again immediately
-			log.Println("-", goal.GoalID, "Subscribed for the slot:", start.Local().Format(appDateTimeLocale))
-		}
-	}
-
-	for { // loop
-		select {
-		case <-aliveTicker.C:
-			log.Println("-", goal.GoalID, "alive")
-		case <-succCh:
-			attempt()
-		case <-attemptTicker.C:
-			attempt()
-		}
-	}
-}
-
-func convConfTimeRanges(ranges []confTimeRanges) [][2]time.Time {
-	result := make([][2]time.Time, 0, len(ranges))
-
-	for _, r := range ranges {
-		if r.Start == nil || r.End == nil {
-			continue
-		}
-
-		result = append(result, [2]time.Time{time.Time(*r.Start), time.Time(*r.End)})
-	}
+		log.Println("Err", err)
 
-	return result
-}
-
-func interactiveGoalDecision(goals []domain.Goal) []domain.Goal {
-	if len(goals) < 1 {
-		return []domain.Goal{}
+		stop()
+		os.Exit(2) //nolint:mnd // usage error.
 	}
 
-	if len(goals) == 1 {
-		log.Println("a goal has been chosen automatically:")
-
-		for i := range goals {
-			fmt.Printf("%7v - %-25s - %s\n", goals[i].GoalID, goals[i].Name, goals[i].Status)
-		}
+	flags.out = out
+	flags.metrics = schoolgql.NewMetrics()
 
-		return []domain.Goal{goals[0]}
+	name := flag.Arg(0)
+	if name == "" {
+		name = commandWatch
 	}
 
-	scanner := bufio.NewReader(os.Stdin)
+	cmd, ok := commands[name]
+	if !ok {
+		log.Println("Err unknown command:", name)
+		flag.Usage()
 
-	availableGoalIDs := make(map[int]int, len(goals))
-	for i, g := range goals {
-		availableGoalIDs[g.GoalID] = i
+		stop()
+		os.Exit(2) //nolint:mnd // usage error.
 	}
 
-inpLoop:
-	for {
-		for i := range goals {
-			fmt.Printf("%7v - %-25s - %s\n", goals[i].GoalID, goals[i].Name, goals[i].Status)
-		}
-
-		fmt.Print("Choose goals, comma separated or \"all\": ")
-
-		input, err := scanner.ReadBytes('\n')
-		if err != nil {
-			log.Println("Err Read bytes stdin:", err)
-
-			continue
-		}
-
-		if input[len(input)-1] == '\n' {
-			input = input[:len(input)-1]
-		}
-
-		if string(input) == "all" {
-			return goals
-		}
-
-		goalIDs := strings.Split(strings.ReplaceAll(string(input), " ", ""), ",")
-		result := make([]domain.Goal, 0, len(goalIDs))
-
-		if len(goalIDs) < 1 {
-			continue
-		}
-
-		for _, inp := range goalIDs {
-			inpGoalID, err := strconv.Atoi(inp)
-			if err != nil {
-				log.Println("Err given GoalID is not valid")
-
-				continue inpLoop
-			}
-
-			if srcID, ok := availableGoalIDs[inpGoalID]; ok {
-				result = append(result, goals[srcID])
-			} else {
-				log.Println("Err given GoalID is not valid")
-
-				continue inpLoop
-			}
-		}
-
-		return result
+	var args []string
+	if flag.NArg() > 1 {
+		args = flag.Args()[1:]
 	}
-}
 
-func (t *ConfTime) UnmarshalYAML(unmarshal func(interface{}) error) error {
-	var str string
-
-	err := unmarshal(&str)
+	err = cmd.run(ctx, flags, args)
 	if err != nil {
-		return fmt.Errorf("config.UnmarshalYAML unmarshal failed: %w", err)
-	}
+		log.Println("Err "+name+":", err)
 
-	vt, err := time.ParseInLocation(appDateTimeLocale, str, time.Local)
-	if err != nil {
-		return fmt.Errorf("config.UnmarshalYAML url.Parse failed: %w", err)
+		stop()
+		os.Exit(1)
 	}
-
-	*t = ConfTime(vt)
-
-	return nil
 }
 
 func PrintRanges(ranges [][2]time.Time) {
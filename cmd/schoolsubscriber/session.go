@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/eldarbr/go-auth/pkg/config"
+	"github.com/eldarbr/schoolsubscriber/internal/domain"
+	"github.com/eldarbr/schoolsubscriber/internal/schoolgql"
+	"github.com/eldarbr/schoolsubscriber/internal/tokencache"
+)
+
+var (
+	ErrNoConfig       = errors.New("the command requires a config, provide it with -c")
+	ErrNoCredentials  = errors.New("the command requires the username and the password, provide them with -u and -p")
+	ErrNoRanges       = errors.New("no ranges")
+	ErrNothingToCheck = errors.New("no goals to review")
+)
+
+// globalFlags are shared by all the commands.
+type globalFlags struct {
+	username   string
+	password   string
+	conf       string
+	goals      string
+	dryRun     bool
+	output     string
+	endpoint   string
+	contextURL string
+	tokenCache bool
+	verbose    bool
+	out        *output
+	metrics    *schoolgql.Metrics
+}
+
+// loadConfig reads and validates the config. Without -c the zero config is returned
+// unless the command requires one.
+func loadConfig(flags globalFlags, required bool) (appConf, *time.Location, error) {
+	var conf appConf
+
+	if flags.conf == "" {
+		if required {
+			return conf, nil, ErrNoConfig
+		}
+
+		return conf, time.Local, nil
+	}
+
+	err := config.ParseConfig(flags.conf, &conf)
+	if err != nil {
+		return conf, nil, fmt.Errorf("read config: %w", err)
+	}
+
+	err = conf.validate()
+	if err != nil {
+		return conf, nil, fmt.Errorf("validate config: %w", err)
+	}
+
+	loc, err := conf.location()
+	if err != nil {
+		return conf, nil, fmt.Errorf("config timezone: %w", err)
+	}
+
+	return conf, loc, nil
+}
+
+// connect logs in and creates the domain.
+func connect(ctx context.Context, flags globalFlags, conf appConf, bot domain.Notificator) (*domain.Domain, error) {
+	if flags.username == "" || flags.password == "" {
+		return nil, ErrNoCredentials
+	}
+
+	middlewares := []schoolgql.Middleware{flags.metrics.Middleware, schoolgql.Retry}
+	if flags.verbose {
+		middlewares = append(middlewares, schoolgql.Logging) // every attempt.
+	}
+
+	gql := schoolgql.NewClient(nil, flags.endpoint, middlewares...)
+
+	var override schoolgql.EduContext
+	if conf.EduContext != nil {
+		override = schoolgql.EduContext{
+			SchoolID:  conf.EduContext.SchoolID,
+			ProductID: conf.EduContext.ProductID,
+			OrgUnitID: conf.EduContext.OrgUnitID,
+		}
+	}
+
+	gql.SetEduContext(flags.contextURL, override) // an empty url follows the endpoint.
+
+	var cachePath string
+
+	if flags.tokenCache {
+		path, err := tokencache.DefaultPath()
+		if err != nil {
+			return nil, fmt.Errorf("token cache: %w", err)
+		}
+
+		cachePath = path
+	}
+
+	client, err := domain.NewDomain(ctx, gql, newSessionToken(flags.username, flags.password, cachePath),
+		flags.username, bot)
+	if err != nil {
+		return nil, fmt.Errorf("new domain: %w", err)
+	}
+
+	return client, nil
+}
+
+// logRequests logs the metrics of the requests to the platform.
+func logRequests(metrics *schoolgql.Metrics) {
+	stats := metrics.Stats()
+
+	for _, operation := range slices.Sorted(maps.Keys(stats)) {
+		opStats := stats[operation]
+		log.Println("-", operation, "requests:", opStats.Calls, "failed:", opStats.Failures,
+			"average:", (opStats.Duration / time.Duration(opStats.Calls)).Round(time.Millisecond))
+	}
+}
+
+// selectGoals returns the goals in evaluation chosen with -g, the select config key or interactively.
+func selectGoals(ctx context.Context, client *domain.Domain, conf appConf, flags globalFlags,
+) ([]domain.Goal, error) {
+	goals, err := client.GetCurrentGoals(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get current goals: %w", err)
+	}
+
+	goals = domain.GoalsFilterEvaluated(goals)
+	if len(goals) < 1 {
+		return nil, ErrNothingToCheck
+	}
+
+	selectors := conf.Select
+	if flags.goals != "" {
+		selectors = domain.SplitSelectors(flags.goals)
+	}
+
+	chosen, err := chooseGoals(ctx, goals, selectors)
+	if err != nil {
+		return nil, fmt.Errorf("choose goals: %w", err)
+	}
+
+	return chosen, nil
+}
+
+// search is what the commands looking for the slots share.
+type search struct {
+	worker    *watcher
+	params    []domain.SubscribeParams
+	summaries []goalSummary
+}
+
+// newSearch prepares the schedules, the export and the parameters of the goals. The export feed
+// is served when serveExport is set.
+func newSearch(ctx context.Context, client *domain.Domain, conf appConf, loc *time.Location,
+	goals []domain.Goal, flags globalFlags, serveExport bool,
+) (search, error) {
+	schedule, err := conf.schedule(loc)
+	if err != nil {
+		return search{}, fmt.Errorf("parse time ranges: %w", err)
+	}
+
+	poll, err := conf.Polling.poller(loc)
+	if err != nil {
+		return search{}, err
+	}
+
+	feed, err := openExport(ctx, conf.Export, serveExport)
+	if err != nil {
+		return search{}, fmt.Errorf("export: %w", err)
+	}
+
+	client.Ledger().SetBuffer(conf.Buffer)
+	client.SetDryRun(flags.dryRun)
+
+	if feed != nil {
+		for _, booking := range feed.Bookings() {
+			if booking.End().After(time.Now()) {
+				client.Ledger().Add(booking)
+			}
+		}
+	}
+
+	for _, goal := range goals {
+		err = client.SeedLedger(ctx, goal)
+		if err != nil {
+			log.Println("-", goal.GoalID, "Err Seed ledger:", err)
+		}
+	}
+
+	result := search{
+		worker: &watcher{
+			client:    client,
+			schedule:  schedule,
+			schedules: make(map[int]searchSchedule),
+			feed:      feed,
+			once:      flags.dryRun,
+			out:       flags.out,
+			poll:      poll,
+		},
+		params:    make([]domain.SubscribeParams, 0, len(goals)),
+		summaries: make([]goalSummary, 0, len(goals)),
+	}
+
+	for _, goal := range goals {
+		goalParams, err := conf.goalParams(goal, loc)
+		if err != nil {
+			log.Println("-", goal.GoalID, "Err Goal settings:", err)
+
+			continue
+		}
+
+		goalSchedule, err := conf.goalSchedule(goal, loc, schedule)
+		if err != nil {
+			log.Println("-", goal.GoalID, "Err Goal ranges:", err)
+
+			continue
+		}
+
+		if len(goalSchedule.Ranges.Rules) < 1 {
+			log.Println("-", goal.GoalID, "Warn neither the goal block nor the common config has ranges, nothing is searched")
+		}
+
+		result.worker.schedules[goal.GoalID] = goalSchedule
+		result.params = append(result.params, goalParams)
+		result.summaries = append(result.summaries, goalSummary{Goal: goal})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/eldarbr/schoolauth"
+	"github.com/eldarbr/schoolsubscriber/internal/tokencache"
+)
+
+// tokenRefreshAhead is how long before the expiry the cached token is refreshed.
+const tokenRefreshAhead = 5 * time.Minute
+
+// sessionToken serializes the access to the token. Without the cache path it relies on the managed
+// token, which keeps the loaded token in memory after Invalidate, so it is replaced by a new one
+// to really log in again. With the cache path the token is kept in the token cache across the runs.
+// Only the current token is invalidated, so the requests rejected together log in once.
+type sessionToken struct {
+	mutex     sync.Mutex
+	username  string
+	password  string
+	managed   *schoolauth.ManagedToken
+	current   string // the last token of the managed one.
+	cachePath string
+	cached    tokencache.Entry
+}
+
+func newSessionToken(username, password, cachePath string) *sessionToken {
+	return &sessionToken{
+		mutex:     sync.Mutex{},
+		username:  username,
+		password:  password,
+		managed:   schoolauth.NewManagedToken(username, password, nil),
+		current:   "",
+		cachePath: cachePath,
+		cached:    tokencache.Entry{},
+	}
+}
+
+func (t *sessionToken) Get(ctx context.Context) (string, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.cachePath != "" {
+		return t.getCached(ctx)
+	}
+
+	token, err := t.managed.Get(ctx)
+	if err != nil {
+		return "", fmt.Errorf("managed token: %w", err)
+	}
+
+	t.current = token
+
+	return token, nil
+}
+
+func (t *sessionToken) Invalidate(token string) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.cachePath != "" {
+		if t.cached.Token != token {
+			return nil // already replaced.
+		}
+
+		t.cached = tokencache.Entry{}
+
+		return tokencache.Remove(t.cachePath) //nolint:wrapcheck // descriptive.
+	}
+
+	if t.current != token {
+		return nil // already replaced.
+	}
+
+	err := t.managed.Invalidate()
+	if err != nil {
+		return fmt.Errorf("managed token: %w", err)
+	}
+
+	t.managed = schoolauth.NewManagedToken(t.username, t.password, nil)
+	t.current = ""
+
+	return nil
+}
+
+// getCached reuses the cached token until it is about to expire, then logs in again. While
+// the login fails the cached token is used until it really expires.
+func (t *sessionToken) getCached(ctx context.Context) (string, error) {
+	now := time.Now()
+
+	if !t.cached.ValidFor(now, tokenRefreshAhead) {
+		entry, err := tokencache.Load(t.cachePath, t.username)
+		if err == nil {
+			t.cached = entry
+		} else if !errors.Is(err, os.ErrNotExist) {
+			log.Println("Err Token cache:", err)
+		}
+	}
+
+	if t.cached.ValidFor(now, tokenRefreshAhead) {
+		return t.cached.Token, nil
+	}
+
+	entry, err := t.login(ctx)
+	if err != nil {
+		if t.cached.ValidFor(now, 0) {
+			log.Println("Err Refresh token:", err)
+
+			return t.cached.Token, nil
+		}
+
+		return "", err
+	}
+
+	t.cached = entry
+
+	err = tokencache.Save(t.cachePath, entry)
+	if err != nil {
+		log.Println("Err Save token cache:", err)
+	}
+
+	return entry.Token, nil
+}
+
+// login gets a new token, its expiry is taken from the JWT.
+func (t *sessionToken) login(ctx context.Context) (tokencache.Entry, error) {
+	auth, err := schoolauth.Auth(ctx, t.username, t.password)
+	if err != nil {
+		return tokencache.Entry{}, fmt.Errorf("login: %w", err)
+	}
+
+	expires, err := tokencache.Expiry(auth.AuthToken)
+	if err != nil {
+		log.Println("Err Token expiry:", err)
+
+		expires = time.Unix(auth.Expires, 0)
+	}
+
+	return tokencache.Entry{Login: t.username, Token: auth.AuthToken, Expires: expires}, nil
+}
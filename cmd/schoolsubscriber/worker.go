@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eldarbr/schoolsubscriber/internal/calfeed"
+	"github.com/eldarbr/schoolsubscriber/internal/domain"
+	"github.com/eldarbr/schoolsubscriber/internal/poller"
+	"github.com/eldarbr/schoolsubscriber/internal/timeranges"
+)
+
+type goalSummary struct {
+	Goal     domain.Goal      `json:"goal"`
+	Bookings []domain.Booking `json:"bookings"`
+	Attempts int              `json:"attempts"`
+	Errors   int              `json:"errors"`
+	Covered  bool             `json:"covered"`
+	Limited  bool             `json:"limited"` // the goal has as many upcoming reviews as max_bookings allows.
+}
+
+// searchSchedule holds the configured ranges and the busy time, both expanded on every poll.
+type searchSchedule struct {
+	Ranges    timeranges.Schedule
+	Blackouts timeranges.Schedule
+}
+
+func (s searchSchedule) fill(params *domain.SubscribeParams, now time.Time) {
+	params.Ranges, params.RangeRules = s.Ranges.Windows(now)
+
+	// the one-off ranges might end past the horizon, the busy time has to cover them too.
+	blackouts := s.Blackouts
+	for _, r := range params.Ranges {
+		blackouts.Horizon = max(blackouts.Horizon, r[1].Sub(now)+blackoutsMargin)
+	}
+
+	params.Blackouts, _ = blackouts.Windows(now)
+}
+
+// watcher holds what the goal workers share.
+type watcher struct {
+	client    *domain.Domain
+	schedule  searchSchedule
+	schedules map[int]searchSchedule // the goals with their own ranges.
+	feed      *calfeed.Feed          // nil when the export is off.
+	once      bool                   // a single attempt, for the dry run.
+	out       *output
+	poll      poller.Poller // copied by every worker.
+}
+
+// scheduleFor returns the schedule of the goal.
+func (w *watcher) scheduleFor(goalID int) searchSchedule {
+	if schedule, ok := w.schedules[goalID]; ok {
+		return schedule
+	}
+
+	return w.schedule
+}
+
+// attemptWorker polls the slots for the goal, the ranges are expanded from the schedule on every poll.
+func (w *watcher) attemptWorker(ctx context.Context, params domain.SubscribeParams, summary *goalSummary,
+	group *sync.WaitGroup,
+) {
+	if group != nil {
+		defer group.Done()
+	}
+
+	goal := summary.Goal
+	client, schedule := w.client, w.scheduleFor(goal.GoalID)
+
+	taskID, _, err := client.GetTaskIDAnswerID(ctx, goal.GoalID)
+	if err != nil {
+		log.Println("-", goal.GoalID, "Err Get task and answer ids: ", err)
+		w.out.event(eventError, &goal, err)
+
+		summary.Errors++
+
+		return
+	}
+
+	params.TaskID = taskID
+
+	log.Println("-", goal.GoalID, "alive")
+	w.out.event(eventStarted, &goal, nil)
+
+	aliveTicker := time.NewTicker(aliveProbePeriod)
+	defer aliveTicker.Stop()
+
+	goalPoller := w.poll
+
+	attemptTimer := time.NewTimer(goalPoller.Interval)
+	defer attemptTimer.Stop()
+
+	coveredTicker := time.NewTicker(coveredCheckPeriod)
+	defer coveredTicker.Stop()
+
+	var (
+		succ    bool
+		succCh  = make(chan bool, 1)
+		booking domain.Booking
+	)
+
+	trigger := func() {
+		select {
+		case succCh <- true:
+		default:
+		}
+	}
+
+	// checkCovered updates the summary with whether all the required reviews are scheduled.
+	checkCovered := func() {
+		progress, progressErr := client.GetReviewsProgress(ctx, goal.GoalID, taskID)
+		if progressErr != nil {
+			if ctx.Err() == nil {
+				log.Println("-", goal.GoalID, "Err Get reviews progress:", progressErr)
+			}
+
+			return
+		}
+
+		covered := progress.Unscheduled() < 1
+		if covered != summary.Covered {
+			if covered {
+				log.Println("-", goal.GoalID, "all the required reviews are scheduled, waiting for a cancellation")
+				w.out.event(eventCovered, &goal, nil)
+			} else {
+				log.Printf("- %d %d review(s) to schedule\n", goal.GoalID, progress.Unscheduled())
+			}
+		}
+
+		summary.Covered = covered
+	}
+
+	checkCovered()
+	trigger() // initial tick
+
+	attempt := func() error {
+		if summary.Covered {
+			return nil
+		}
+
+		summary.Attempts++
+
+		schedule.fill(&params, time.Now())
+
+		booking, succ, err = client.AttemptSubscribe(ctx, params)
+		if w.checkLimited(summary, err) {
+			return nil
+		}
+
+		if errors.Is(err, domain.ErrFullyScheduled) {
+			log.Println("-", goal.GoalID, "all the required reviews are scheduled, waiting for a cancellation")
+			w.out.event(eventCovered, &goal, nil)
+
+			summary.Covered = true
+
+			return nil
+		}
+
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Println("-", goal.GoalID, "Err Attempt:", err)
+				w.out.event(eventError, &goal, err)
+
+				summary.Errors++
+			}
+
+			return err
+		}
+
+		if succ {
+			w.record(summary, booking)
+
+			checkCovered()
+			trigger() // try again immediately
+		}
+
+		return nil
+	}
+
+	// poll attempts and schedules the next attempt.
+	poll := func() {
+		err := attempt()
+		attemptTimer.Reset(w.nextPoll(&goalPoller, goal.GoalID, err))
+	}
+
+	if w.once {
+		_ = attempt()
+
+		return
+	}
+
+	for { // loop
+		select {
+		case <-ctx.Done():
+			log.Println("-", goal.GoalID, "stopped")
+			w.out.event(eventStopped, &goal, nil)
+
+			return
+		case <-aliveTicker.C:
+			log.Println("-", goal.GoalID, "alive")
+			w.out.event(eventAlive, &goal, nil)
+		case <-succCh:
+			poll()
+		case <-attemptTimer.C:
+			poll()
+		case <-coveredTicker.C:
+			if summary.Covered {
+				checkCovered()
+				trigger()
+			}
+		}
+	}
+}
+
+// nextPoll returns the delay before the next attempt, logging the backoff.
+func (w *watcher) nextPoll(goalPoller *poller.Poller, label any, err error) time.Duration {
+	delay := goalPoller.Next(time.Now(), err)
+	if goalPoller.Failures() > 0 {
+		log.Println("-", label, "backing off for", delay.Round(time.Second), "after", goalPoller.Failures(), "error(s)")
+	}
+
+	return delay
+}
+
+// checkLimited tracks whether the goal reached max_bookings, the error is the one of the attempt.
+func (w *watcher) checkLimited(summary *goalSummary, err error) bool {
+	limited := errors.Is(err, domain.ErrMaxBookings)
+	if limited && !summary.Limited {
+		log.Println("-", summary.Goal.GoalID, "reached max_bookings, waiting for a review to pass")
+		w.out.event(eventLimited, &summary.Goal, nil)
+	}
+
+	summary.Limited = limited
+
+	return limited
+}
+
+// record adds the booking to the summary and to the export.
+func (w *watcher) record(summary *goalSummary, booking domain.Booking) {
+	summary.Bookings = append(summary.Bookings, booking)
+
+	log.Println("-", booking.Goal.GoalID, "Subscribed for the slot:", formatBooking(booking))
+	w.out.booked(booking)
+
+	w.export(booking)
+}
+
+// export adds the booking to the calendar feed.
+func (w *watcher) export(booking domain.Booking) {
+	if w.feed == nil || booking.DryRun {
+		return
+	}
+
+	err := w.feed.Add(booking)
+	if err != nil {
+		log.Println("-", booking.Goal.GoalID, "Err Export booking:", err)
+	}
+}
+
+func formatBooking(booking domain.Booking) string {
+	return booking.Start.Local().Format(appDateTimeLocale) + " " + booking.ModeName()
+}
+
+// printSummaries streams the summaries in the json mode, or prints the text report.
+func (w *watcher) printSummaries(summaries []goalSummary, report string) {
+	if !w.out.json {
+		fmt.Print(report)
+
+		return
+	}
+
+	for i := range summaries {
+		w.out.line(watchEvent{Time: time.Now(), Event: eventSummary, Goal: &summaries[i].Goal, Summary: &summaries[i]})
+	}
+}
+
+func formatSummaries(summaries []goalSummary) string {
+	builder := strings.Builder{}
+
+	builder.WriteString("Summary:\n")
+
+	for _, summary := range summaries {
+		fmt.Fprintf(&builder, "%7v - %-25s - attempts: %d, errors: %d, booked: %d, all scheduled: %t\n",
+			summary.Goal.GoalID, summary.Goal.Name, summary.Attempts, summary.Errors, len(summary.Bookings),
+			summary.Covered)
+
+		for _, booking := range summary.Bookings {
+			fmt.Fprintf(&builder, "\t- %s\n", formatBooking(booking))
+		}
+	}
+
+	return builder.String()
+}
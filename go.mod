@@ -5,10 +5,10 @@ go 1.23.5
 require (
 	github.com/eldarbr/go-auth v1.1.2
 	github.com/eldarbr/schoolauth v1.0.3
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/google/uuid v1.6.0 // indirect
 	golang.org/x/crypto v0.34.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,232 @@
+package calfeed
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eldarbr/schoolsubscriber/internal/domain"
+	"github.com/eldarbr/schoolsubscriber/internal/ical"
+)
+
+const (
+	calendarName = "P2P reviews"
+	uidSuffix    = "@schoolsubscriber"
+	// goalUIDPrefix marks the UID of a booking without an id, it is made of the goal and the start.
+	goalUIDPrefix = "goal-"
+
+	propGoalID  = "X-SCHOOLSUBSCRIBER-GOAL-ID"
+	propProject = "X-SCHOOLSUBSCRIBER-PROJECT"
+	propOnline  = "X-SCHOOLSUBSCRIBER-ONLINE"
+
+	feedFileMode = 0o600 // the feed lists the projects and the booking ids.
+)
+
+// Feed keeps the bookings in an iCalendar file, it also serves the file over HTTP.
+type Feed struct {
+	path     string
+	mutex    sync.RWMutex
+	bookings []domain.Booking
+}
+
+// OpenFeed reads the bookings previously written to the file, a missing file is fine.
+func OpenFeed(path string) (*Feed, error) {
+	bookings, err := readBookings(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Feed{path: path, bookings: bookings}, nil
+}
+
+// Add records the booking and rewrites the file. The file is read again first, so that the bookings
+// added by another process meanwhile are kept.
+func (f *Feed) Add(booking domain.Booking) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	bookings, err := readBookings(f.path)
+	if err != nil {
+		return err
+	}
+
+	if !slices.ContainsFunc(bookings, func(known domain.Booking) bool { return sameBooking(known, booking) }) {
+		bookings = append(bookings, booking)
+	}
+
+	slices.SortFunc(bookings, func(a, b domain.Booking) int { return a.Start.Compare(b.Start) })
+	f.bookings = bookings
+
+	return f.write()
+}
+
+// readBookings parses the file, a missing file has no bookings.
+func readBookings(path string) ([]domain.Booking, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read feed: %w", err)
+	}
+
+	events, err := ical.Parse(bytes.NewReader(content), time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("parse feed: %w", err)
+	}
+
+	result := make([]domain.Booking, 0, len(events))
+	for _, event := range events {
+		result = append(result, eventToBooking(event))
+	}
+
+	return result, nil
+}
+
+// sameBooking compares the booking ids, the dry run bookings without one by the goal and the start.
+func sameBooking(a, b domain.Booking) bool {
+	if a.ID != "" || b.ID != "" {
+		return a.ID == b.ID
+	}
+
+	return a.Goal.GoalID == b.Goal.GoalID && a.Start.Equal(b.Start)
+}
+
+// Bookings returns the recorded bookings ordered by the start.
+func (f *Feed) Bookings() []domain.Booking {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	return slices.Clone(f.bookings)
+}
+
+func (f *Feed) ServeHTTP(writer http.ResponseWriter, _ *http.Request) {
+	buf := bytes.Buffer{}
+
+	err := ical.Encode(&buf, calendarName, f.events())
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	writer.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	_, _ = writer.Write(buf.Bytes())
+}
+
+// events returns the events of the file as it is now, it might be written by another process.
+// The known bookings are used if the file can not be read.
+func (f *Feed) events() []ical.Event {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	bookings, err := readBookings(f.path)
+	if err != nil {
+		log.Println("Err Read feed:", err)
+	} else {
+		f.bookings = bookings
+	}
+
+	result := make([]ical.Event, 0, len(f.bookings))
+	for _, booking := range f.bookings {
+		result = append(result, bookingToEvent(booking))
+	}
+
+	return result
+}
+
+// write replaces the file atomically. Must be called with the mutex held.
+func (f *Feed) write() error {
+	events := make([]ical.Event, 0, len(f.bookings))
+	for _, booking := range f.bookings {
+		events = append(events, bookingToEvent(booking))
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp feed: %w", err)
+	}
+
+	defer os.Remove(tmp.Name())
+
+	err = ical.Encode(tmp, calendarName, events)
+	if err != nil {
+		tmp.Close()
+
+		return fmt.Errorf("encode feed: %w", err)
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return fmt.Errorf("close temp feed: %w", err)
+	}
+
+	err = os.Chmod(tmp.Name(), feedFileMode)
+	if err != nil {
+		return fmt.Errorf("chmod feed: %w", err)
+	}
+
+	err = os.Rename(tmp.Name(), f.path)
+	if err != nil {
+		return fmt.Errorf("replace feed: %w", err)
+	}
+
+	return nil
+}
+
+func bookingToEvent(booking domain.Booking) ical.Event {
+	description := fmt.Sprintf("Project: %s\nGoal: %d\nMode: %s\nBooking: %s",
+		booking.Goal.Name, booking.Goal.GoalID, booking.ModeName(), booking.ID)
+
+	return ical.Event{
+		UID:         bookingUID(booking),
+		Summary:     "P2P review: " + booking.Goal.Name,
+		Description: description,
+		Start:       booking.Start,
+		End:         booking.End(),
+		Extra: map[string]string{
+			propGoalID:  strconv.Itoa(booking.Goal.GoalID),
+			propProject: booking.Goal.Name,
+			propOnline:  strconv.FormatBool(booking.Online),
+		},
+	}
+}
+
+// bookingUID is made of the booking id, or of the goal and the start for a booking without one,
+// e.g. a dry run one, so that such bookings do not replace each other.
+func bookingUID(booking domain.Booking) string {
+	if booking.ID == "" {
+		return fmt.Sprintf("%s%d-%s%s", goalUIDPrefix, booking.Goal.GoalID,
+			booking.Start.UTC().Format("20060102T150405Z"), uidSuffix)
+	}
+
+	return booking.ID + uidSuffix
+}
+
+func eventToBooking(event ical.Event) domain.Booking {
+	goalID, _ := strconv.Atoi(event.Extra[propGoalID])
+	online, _ := strconv.ParseBool(event.Extra[propOnline])
+
+	bookingID := strings.TrimSuffix(event.UID, uidSuffix)
+	if strings.HasPrefix(bookingID, goalUIDPrefix) {
+		bookingID = ""
+	}
+
+	return domain.Booking{
+		ID:       bookingID,
+		Goal:     domain.Goal{GoalID: goalID, Name: event.Extra[propProject], Status: ""},
+		Start:    event.Start,
+		Duration: event.End.Sub(event.Start),
+		Online:   online,
+	}
+}
@@ -0,0 +1,187 @@
+package domain
+
+import (
+	"cmp"
+	"slices"
+)
+
+const (
+	// planSlotsPerGoal bounds the best ranked slots of a goal the planner considers.
+	planSlotsPerGoal = 24
+	// planSearchBudget bounds the search steps, the best plan found by then is used.
+	planSearchBudget = 200000
+)
+
+// planDemand is a review to plan, a goal needing several reviews has a demand per review.
+type planDemand struct {
+	goal  int // the index of the goal candidates.
+	level int // the index of the goal priority, 0 is the highest priority.
+	nth   int // the slot of the n-th review of the goal follows the slot of the previous one.
+}
+
+// planValue compares the plans: the more reviews of the higher priorities the better, then
+// the lower total score the better.
+type planValue struct {
+	counts []int // per priority level.
+	score  float64
+}
+
+func (v planValue) better(other planValue) bool {
+	for i := range v.counts {
+		if v.counts[i] != other.counts[i] {
+			return v.counts[i] > other.counts[i]
+		}
+	}
+
+	return v.score < other.score
+}
+
+// planSearch is a branch and bound search of the slot per demand. The demands take the slots in
+// the ranked order, so the first plan found is the greedy one, and the branches that can not beat
+// the best plan found so far are cut. Past the budget the best plan found is kept.
+type planSearch struct {
+	candidates []GoalCandidates
+	scores     [][]float64 // per goal and slot.
+	minScores  []float64   // per goal.
+	demands    []planDemand
+	ledger     *Ledger // the scratch ledger holding the chosen slots.
+	chosen     []int   // the slot index per demand, -1 when the demand is not served.
+	current    planValue
+	best       planValue
+	bestChosen []int
+	steps      int
+}
+
+func newPlanSearch(candidates []GoalCandidates, scratch *Ledger) *planSearch {
+	search := &planSearch{
+		candidates: make([]GoalCandidates, len(candidates)),
+		scores:     make([][]float64, len(candidates)),
+		minScores:  make([]float64, len(candidates)),
+		ledger:     scratch,
+	}
+
+	for i, goal := range candidates {
+		goal.Slots = goal.Slots[:min(len(goal.Slots), planSlotsPerGoal)]
+		search.candidates[i] = goal
+		search.scores[i] = make([]float64, len(goal.Slots))
+
+		for j, slot := range goal.Slots {
+			search.scores[i][j] = goal.Params.Ranking.Score(slot)
+			if j == 0 || search.scores[i][j] < search.minScores[i] {
+				search.minScores[i] = search.scores[i][j]
+			}
+		}
+	}
+
+	priorities := make([]int, 0, len(candidates))
+	for _, goal := range candidates {
+		priorities = append(priorities, goal.Params.Priority)
+	}
+
+	slices.Sort(priorities)
+	priorities = slices.Compact(priorities)
+	slices.Reverse(priorities)
+
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+
+	// the goals with fewer slots first cut the branches earlier.
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Or(
+			cmp.Compare(candidates[b].Params.Priority, candidates[a].Params.Priority),
+			cmp.Compare(len(search.candidates[a].Slots), len(search.candidates[b].Slots)),
+		)
+	})
+
+	for _, goal := range order {
+		level := slices.Index(priorities, candidates[goal].Params.Priority)
+
+		for nth := range min(candidates[goal].Needed, len(search.candidates[goal].Slots)) {
+			search.demands = append(search.demands, planDemand{goal: goal, level: level, nth: nth})
+		}
+	}
+
+	search.chosen = make([]int, len(search.demands))
+	search.bestChosen = make([]int, len(search.demands))
+	search.current.counts = make([]int, len(priorities))
+	search.best.counts = make([]int, len(priorities))
+
+	for i := range search.demands {
+		search.chosen[i], search.bestChosen[i] = -1, -1
+	}
+
+	for i := range search.best.counts {
+		search.best.counts[i] = -1 // any plan is better.
+	}
+
+	return search
+}
+
+// run serves the demands starting with the index.
+func (s *planSearch) run(index int) {
+	s.steps++
+
+	if index == len(s.demands) {
+		if s.current.better(s.best) {
+			s.best = planValue{counts: slices.Clone(s.current.counts), score: s.current.score}
+			copy(s.bestChosen, s.chosen)
+		}
+
+		return
+	}
+
+	if s.steps > planSearchBudget || !s.bound(index).better(s.best) {
+		return
+	}
+
+	demand := s.demands[index]
+	first := 0
+
+	if demand.nth > 0 {
+		previous := s.chosen[index-1]
+		if previous < 0 {
+			// the previous review of the goal is not served, neither is this one.
+			s.run(index + 1)
+
+			return
+		}
+
+		first = previous + 1
+	}
+
+	slots := s.candidates[demand.goal].Slots
+
+	for slot := first; slot < len(slots); slot++ {
+		key, ok := s.ledger.Reserve(slots[slot].Start, slots[slot].End())
+		if !ok {
+			continue
+		}
+
+		s.chosen[index] = slot
+		s.current.counts[demand.level]++
+		s.current.score += s.scores[demand.goal][slot]
+
+		s.run(index + 1)
+
+		s.current.score -= s.scores[demand.goal][slot]
+		s.current.counts[demand.level]--
+		s.chosen[index] = -1
+		s.ledger.Release(key)
+	}
+
+	s.run(index + 1)
+}
+
+// bound is the value of the plan if all the demands starting with the index get their best slots.
+func (s *planSearch) bound(index int) planValue {
+	result := planValue{counts: slices.Clone(s.current.counts), score: s.current.score}
+
+	for _, demand := range s.demands[index:] {
+		result.counts[demand.level]++
+		result.score += s.minScores[demand.goal]
+	}
+
+	return result
+}
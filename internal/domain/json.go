@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// MarshalJSON adds the end and the duration in minutes to the slot fields.
+func (s Slot) MarshalJSON() ([]byte, error) {
+	type plain Slot
+
+	result, err := json.Marshal(struct {
+		plain
+		End             time.Time `json:"end"`
+		DurationMinutes float64   `json:"duration_minutes"`
+	}{plain: plain(s), End: s.End(), DurationMinutes: s.Duration.Minutes()})
+	if err != nil {
+		return nil, fmt.Errorf("marshal slot: %w", err)
+	}
+
+	return result, nil
+}
+
+// MarshalJSON adds the end, the duration in minutes and the mode name to the booking fields.
+func (b Booking) MarshalJSON() ([]byte, error) {
+	type plain Booking
+
+	result, err := json.Marshal(struct {
+		plain
+		End             time.Time `json:"end"`
+		DurationMinutes float64   `json:"duration_minutes"`
+		Mode            string    `json:"mode"`
+	}{plain: plain(b), End: b.End(), DurationMinutes: b.Duration.Minutes(), Mode: b.ModeName()})
+	if err != nil {
+		return nil, fmt.Errorf("marshal booking: %w", err)
+	}
+
+	return result, nil
+}
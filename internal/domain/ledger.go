@@ -0,0 +1,152 @@
+package domain
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Ledger keeps the reviews booked by all the goals, so that they neither overlap
+// nor follow each other closer than the buffer.
+type Ledger struct {
+	mutex   sync.Mutex
+	buffer  time.Duration
+	entries map[int]ledgerEntry
+	nextKey int
+}
+
+type ledgerEntry struct {
+	start, end time.Time
+	booking    *Booking // nil while the booking is in flight.
+}
+
+func NewLedger(buffer time.Duration) *Ledger {
+	return &Ledger{
+		buffer:  buffer,
+		entries: make(map[int]ledgerEntry),
+	}
+}
+
+// SetBuffer changes the minimal gap between the consecutive reviews.
+func (l *Ledger) SetBuffer(buffer time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.buffer = buffer
+}
+
+// Clone returns an independent copy, e.g. to try out a plan.
+func (l *Ledger) Clone() *Ledger {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	result := NewLedger(l.buffer)
+	for _, entry := range l.entries {
+		result.put(entry)
+	}
+
+	return result
+}
+
+// Add records a booking made earlier, e.g. before a restart. A booking of the goal at the same
+// start is only recorded once, the same review might be known from several sources.
+func (l *Ledger) Add(booking Booking) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for _, entry := range l.entries {
+		if entry.booking != nil && entry.booking.Goal.GoalID == booking.Goal.GoalID && entry.start.Equal(booking.Start) {
+			return
+		}
+	}
+
+	l.put(ledgerEntry{start: booking.Start, end: booking.End(), booking: &booking})
+}
+
+// Free reports whether a review might be booked at the time.
+func (l *Ledger) Free(start, end time.Time) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.free(start, end)
+}
+
+// Reserve holds the time for a booking in flight. The reservation must be either confirmed
+// or released, the returned key identifies it.
+func (l *Ledger) Reserve(start, end time.Time) (int, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if !l.free(start, end) {
+		return 0, false
+	}
+
+	return l.put(ledgerEntry{start: start, end: end, booking: nil}), true
+}
+
+// Confirm turns the reservation into the booking.
+func (l *Ledger) Confirm(key int, booking Booking) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.entries[key] = ledgerEntry{start: booking.Start, end: booking.End(), booking: &booking}
+}
+
+// Release frees the reserved time.
+func (l *Ledger) Release(key int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	delete(l.entries, key)
+}
+
+// Count returns the number of the bookings of the goal not finished by now.
+func (l *Ledger) Count(goalID int, now time.Time) int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	result := 0
+
+	for _, entry := range l.entries {
+		if entry.booking != nil && entry.booking.Goal.GoalID == goalID && entry.end.After(now) {
+			result++
+		}
+	}
+
+	return result
+}
+
+// Bookings returns the confirmed bookings ordered by the start.
+func (l *Ledger) Bookings() []Booking {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	result := make([]Booking, 0, len(l.entries))
+
+	for _, entry := range l.entries {
+		if entry.booking != nil {
+			result = append(result, *entry.booking)
+		}
+	}
+
+	slices.SortFunc(result, func(a, b Booking) int { return a.Start.Compare(b.Start) })
+
+	return result
+}
+
+func (l *Ledger) put(entry ledgerEntry) int {
+	l.nextKey++
+	l.entries[l.nextKey] = entry
+
+	return l.nextKey
+}
+
+func (l *Ledger) free(start, end time.Time) bool {
+	for _, entry := range l.entries {
+		if start.Before(entry.end.Add(l.buffer)) && entry.start.Add(-l.buffer).Before(end) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// BookingMode chooses whether the reviews are booked online or offline.
+// The zero value books online only.
+type BookingMode int
+
+const (
+	ModeOnline BookingMode = iota
+	ModeOffline
+	// ModeOnlineFirst retries the slot offline when the platform rejects the online booking.
+	ModeOnlineFirst
+	// ModeOfflineFirst retries the slot online when the platform rejects the offline booking.
+	ModeOfflineFirst
+)
+
+var ErrBadMode = errors.New("unknown booking mode")
+
+var modeNames = map[BookingMode]string{
+	ModeOnline:       "online",
+	ModeOffline:      "offline",
+	ModeOnlineFirst:  "online_first",
+	ModeOfflineFirst: "offline_first",
+}
+
+// ParseBookingMode parses one of "online", "offline", "online_first", "offline_first".
+func ParseBookingMode(str string) (BookingMode, error) {
+	str = strings.ToLower(strings.TrimSpace(str))
+
+	for mode, name := range modeNames {
+		if name == str {
+			return mode, nil
+		}
+	}
+
+	return ModeOnline, fmt.Errorf("%w: %q", ErrBadMode, str)
+}
+
+func (m BookingMode) String() string {
+	return modeNames[m]
+}
+
+func (m BookingMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// Attempts lists the isOnline values to try the slot with, in order.
+func (m BookingMode) Attempts() []bool {
+	switch m {
+	case ModeOffline:
+		return []bool{false}
+	case ModeOnlineFirst:
+		return []bool{true, false}
+	case ModeOfflineFirst:
+		return []bool{false, true}
+	default:
+		return []bool{true}
+	}
+}
+
+func onlineName(online bool) string {
+	if online {
+		return modeNames[ModeOnline]
+	}
+
+	return modeNames[ModeOffline]
+}
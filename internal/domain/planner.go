@@ -0,0 +1,155 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+)
+
+// GoalCandidates are the slots a goal might be booked into.
+type GoalCandidates struct {
+	Params   SubscribeParams
+	AnswerID string
+	Needed   int    // the reviews left to schedule.
+	Slots    []Slot // ranked, the most preferred first.
+}
+
+// PlannedSlot is a slot the plan books for a goal.
+type PlannedSlot struct {
+	Goal     Goal        `json:"goal"`
+	AnswerID string      `json:"answer_id"`
+	Slot     Slot        `json:"slot"`
+	Mode     BookingMode `json:"mode"`
+	Score    float64     `json:"score"`
+
+	notificator Notificator
+}
+
+// GetCandidates finds the available slots of the goal free in the ledger and ranks them. The answer
+// is only looked up when there are slots, ErrFullyScheduled is returned if nothing is left to book.
+func (dom *Domain) GetCandidates(ctx context.Context, params SubscribeParams) (GoalCandidates, error) {
+	result := GoalCandidates{Params: params}
+
+	booked := dom.ledger.Count(params.Goal.GoalID, time.Now())
+	if params.MaxBookings > 0 && booked >= params.MaxBookings {
+		return result, ErrMaxBookings
+	}
+
+	// the blackouts are filtered out locally, cutting them out of the ranges would split the request.
+	slots, err := GetSlotsRanges(ctx, dom.gql, dom.tokener, params.TaskID, params.Ranges, params.RangeRules)
+	if err != nil {
+		return result, fmt.Errorf("get slots from the ranges: %w", err)
+	}
+
+	slots = SlotsFilterBusy(slots, params.Blackouts)
+	if !params.Staff {
+		slots = SlotsFilterStaff(slots)
+	}
+	slots = slices.DeleteFunc(slots, func(slot Slot) bool { return !dom.ledger.Free(slot.Start, slot.End()) })
+
+	if len(slots) == 0 {
+		return result, nil
+	}
+
+	log.Printf("Found %d slots\n", len(slots))
+
+	answer, err := dom.GetAnswerEvaluations(ctx, params.Goal.GoalID)
+	if err != nil {
+		return result, fmt.Errorf("get answer evaluations: %w", err)
+	}
+
+	progress := ReviewsProgress{Evaluations: answer}
+
+	// the counters are only needed while the platform lists no P2P entries, see Unscheduled.
+	if len(answer.P2P) == 0 {
+		progress, err = GetReviewsInfo(ctx, dom.gql, dom.tokener, params.TaskID)
+		if err != nil {
+			return result, fmt.Errorf("get reviews info: %w", err)
+		}
+
+		progress.Evaluations = answer
+	}
+
+	if progress.Unscheduled() < 1 {
+		return result, ErrFullyScheduled
+	}
+
+	params.Ranking.Rank(slots)
+
+	result.AnswerID = answer.AnswerID
+	result.Needed = progress.Unscheduled()
+
+	if params.MaxBookings > 0 {
+		result.Needed = min(result.Needed, params.MaxBookings-booked)
+	}
+	result.Slots = slots
+
+	return result, nil
+}
+
+// planned makes the plan entry booking the slot for the goal.
+func (c GoalCandidates) planned(slot Slot) PlannedSlot {
+	return PlannedSlot{
+		Goal:        c.Params.Goal,
+		AnswerID:    c.AnswerID,
+		Slot:        slot,
+		Mode:        c.Params.Mode,
+		Score:       c.Params.Ranking.Score(slot),
+		notificator: c.Params.Notificator,
+	}
+}
+
+// Plan assigns the slots to the goals so that the reviews neither overlap each other nor the ledger
+// bookings. The plan books as many reviews of the goals with the highest priority as possible, then
+// of the next priority and so on, and among such plans it has the lowest total score, see planSearch.
+func (dom *Domain) Plan(candidates []GoalCandidates) []PlannedSlot {
+	search := newPlanSearch(candidates, dom.ledger.Clone())
+	search.run(0)
+
+	var result []PlannedSlot
+
+	for i, demand := range search.demands {
+		if slot := search.bestChosen[i]; slot >= 0 {
+			result = append(result, candidates[demand.goal].planned(candidates[demand.goal].Slots[slot]))
+		}
+	}
+
+	slices.SortFunc(result, func(a, b PlannedSlot) int { return a.Slot.Start.Compare(b.Slot.Start) })
+
+	return result
+}
+
+// BookSlot occupies the slot, one of the candidates, for the goal.
+func (dom *Domain) BookSlot(ctx context.Context, candidates GoalCandidates, slot Slot) (Booking, error) {
+	return dom.book(ctx, candidates.planned(slot))
+}
+
+// BookPlan occupies the planned slots. The slots failed to book are skipped, their errors are joined.
+func (dom *Domain) BookPlan(ctx context.Context, plan []PlannedSlot) ([]Booking, error) {
+	var (
+		result []Booking
+		errs   []error
+	)
+
+	for _, planned := range plan {
+		if ctx.Err() != nil {
+			errs = append(errs, fmt.Errorf("occupy: %w", ctx.Err()))
+
+			break
+		}
+
+		booking, err := dom.book(ctx, planned)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("goal %d at %s: %w", planned.Goal.GoalID, planned.Slot.Start, err))
+
+			continue
+		}
+
+		result = append(result, booking)
+	}
+
+	return result, errors.Join(errs...)
+}
@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+var planBase = time.Date(2026, time.March, 2, 10, 0, 0, 0, time.UTC)
+
+// planSlot is the hour long slot starting the hours after planBase.
+func planSlot(hours int) Slot {
+	return Slot{Start: planBase.Add(time.Duration(hours) * time.Hour), Duration: time.Hour}
+}
+
+// planGoal ranks the slots with the scores keyed by the slot start hour.
+func planGoal(goalID, priority, needed int, scores map[int]float64) GoalCandidates {
+	ranking := SlotRanking{Scorers: []SlotScorer{SlotScoreFunc(func(slot Slot) float64 {
+		return scores[int(slot.Start.Sub(planBase).Hours())]
+	})}}
+
+	slots := make([]Slot, 0, len(scores))
+	for hours := range scores {
+		slots = append(slots, planSlot(hours))
+	}
+
+	ranking.Rank(slots)
+
+	return GoalCandidates{
+		Params: SubscribeParams{Goal: Goal{GoalID: goalID}, Ranking: ranking, Priority: priority},
+		Needed: needed,
+		Slots:  slots,
+	}
+}
+
+func TestPlan(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		candidates []GoalCandidates
+		buffer     time.Duration
+		booked     []int // the start hours of the reviews in the ledger.
+		want       map[int][]int
+	}{
+		{
+			// the greedy plan gives the slot 0 to the goal 1 and leaves the slot 10 to the goal 2.
+			name: "lower total score than greedy",
+			candidates: []GoalCandidates{
+				planGoal(1, 0, 1, map[int]float64{0: 0, 2: 0.5}),
+				planGoal(2, 0, 1, map[int]float64{0: 0, 4: 10}),
+			},
+			want: map[int][]int{1: {2}, 2: {0}},
+		},
+		{
+			// the greedy plan books the slots 0 and 2 for the goal 1 and nothing for the goal 2.
+			name: "more reviews than greedy",
+			candidates: []GoalCandidates{
+				planGoal(1, 0, 2, map[int]float64{0: 0, 2: 1, 4: 2}),
+				planGoal(2, 0, 1, map[int]float64{2: 0}),
+			},
+			want: map[int][]int{1: {0, 4}, 2: {2}},
+		},
+		{
+			name: "higher priority wins despite the score",
+			candidates: []GoalCandidates{
+				planGoal(1, 0, 1, map[int]float64{0: 0}),
+				planGoal(2, 1, 1, map[int]float64{0: 100}),
+			},
+			want: map[int][]int{2: {0}},
+		},
+		{
+			name: "ledger bookings are kept",
+			candidates: []GoalCandidates{
+				planGoal(1, 0, 1, map[int]float64{0: 0, 2: 1}),
+			},
+			booked: []int{0},
+			want:   map[int][]int{1: {2}},
+		},
+		{
+			name: "buffer between the reviews",
+			candidates: []GoalCandidates{
+				planGoal(1, 0, 2, map[int]float64{0: 0, 1: 0, 2: 5}),
+			},
+			buffer: 30 * time.Minute,
+			want:   map[int][]int{1: {0, 2}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			dom := &Domain{ledger: NewLedger(test.buffer)}
+			for _, hours := range test.booked {
+				dom.ledger.Add(Booking{Goal: Goal{GoalID: 100}, Start: planSlot(hours).Start, Duration: time.Hour})
+			}
+
+			got := make(map[int][]int)
+			for _, planned := range dom.Plan(test.candidates) {
+				got[planned.Goal.GoalID] = append(got[planned.Goal.GoalID],
+					int(planned.Slot.Start.Sub(planBase).Hours()))
+			}
+
+			for goalID := range got {
+				slices.Sort(got[goalID])
+			}
+
+			if len(got) != len(test.want) {
+				t.Fatalf("Plan() = %v, want %v", got, test.want)
+			}
+
+			for goalID, want := range test.want {
+				if !slices.Equal(got[goalID], want) {
+					t.Fatalf("Plan() = %v, want %v", got, test.want)
+				}
+			}
+		})
+	}
+}
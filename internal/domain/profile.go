@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eldarbr/schoolsubscriber/internal/schoolgql"
+	"github.com/eldarbr/schoolsubscriber/internal/schoolgql/queries"
+)
+
+// Profile is the public profile of the user.
+type Profile struct {
+	Login            string `json:"login"`
+	Email            string `json:"email"`
+	School           string `json:"school"`
+	Wave             string `json:"wave"`
+	EduForm          string `json:"edu_form"`
+	Level            int    `json:"level"`
+	Experience       int    `json:"experience"`
+	Coins            int    `json:"coins"`
+	Cookies          int    `json:"cookies"`
+	CodeReviewPoints int    `json:"code_review_points"`
+}
+
+// Credentials returns the ids of the user the domain works for.
+func (dom *Domain) Credentials() Credentials {
+	return dom.creds
+}
+
+func (dom *Domain) GetProfile(ctx context.Context) (Profile, error) {
+	req, err := schoolgql.NewRequest(queries.PublicProfileGetPersonalInfo)
+	if err != nil {
+		return Profile{}, fmt.Errorf("new req get personal info: %w", err)
+	}
+
+	req.Variables = queries.VarsPublicProfileGetPersonalInfo{
+		Login:     dom.creds.Login,
+		SchoolID:  dom.creds.SchoolID,
+		StudentID: dom.creds.StudentID,
+		UserID:    dom.creds.UserID,
+	}
+	resp := queries.ResponsePublicProfileGetPersonalInfo{}
+
+	err = do(ctx, dom.gql, dom.tokener, req, &resp)
+	if err != nil {
+		return Profile{}, fmt.Errorf("make req get personal info: %w", err)
+	}
+
+	school21 := resp.Data.School21
+
+	return Profile{
+		Login:            dom.creds.Login,
+		Email:            school21.GetEmailbyUserID,
+		School:           resp.Data.User.GetSchool.ShortName,
+		Wave:             school21.GetStageGroupS21PublicProfile.WaveName,
+		EduForm:          school21.GetStageGroupS21PublicProfile.EduForm,
+		Level:            school21.GetExperiencePublicProfile.Level.LevelCode,
+		Experience:       school21.GetExperiencePublicProfile.Value,
+		Coins:            school21.GetExperiencePublicProfile.CoinsCount,
+		Cookies:          school21.GetExperiencePublicProfile.CookiesCount,
+		CodeReviewPoints: school21.GetExperiencePublicProfile.CodeReviewPoints,
+	}, nil
+}
@@ -10,56 +10,96 @@ import (
 	"sync"
 	"time"
 
+	"github.com/eldarbr/schoolsubscriber/internal/myerrs"
 	"github.com/eldarbr/schoolsubscriber/internal/schoolgql"
 	"github.com/eldarbr/schoolsubscriber/internal/schoolgql/queries"
 )
 
 type Goal struct {
-	GoalID int
-	Name   string
-	Status string
+	GoalID int    `json:"goal_id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
 }
 
 type Domain struct {
 	userID      string
 	studentID   string
+	creds       Credentials
+	gql         *schoolgql.Client
 	tokener     Tokener
 	notificator Notificator
+	notifyGroup sync.WaitGroup
+	ledger      *Ledger
+	dryRun      bool
 }
 
 type Notificator interface {
 	SendMessage(ctx context.Context, msg string) error
 }
 
+// Tokener provides the auth token. Invalidate makes the next Get log in again, unless the token
+// rejected by the platform is not the current one anymore, e.g. another request got a fresh one.
 type Tokener interface {
 	Get(ctx context.Context) (string, error)
+	Invalidate(token string) error
 }
 
+const (
+	// bookingTimeout bounds an in-flight booking that is let to finish after the cancellation.
+	bookingTimeout = 20 * time.Second
+	notifyTimeout  = 10 * time.Second
+	// checkDurationUnit is the unit of the timeslots checkDuration.
+	checkDurationUnit = time.Minute
+)
+
 var (
 	ErrNoSlots   = errors.New("no slots available")
 	ErrNoAnswers = errors.New("no evaluated answers found")
+	// ErrFullyScheduled is returned instead of booking extra reviews for an answer.
+	ErrFullyScheduled = errors.New("all the reviews are already scheduled")
+	// ErrSlotTaken is returned when the slot is too close to another booked review.
+	ErrSlotTaken = errors.New("the time is taken by another review")
+	// ErrMaxBookings is returned when the goal has as many upcoming reviews as it is allowed.
+	ErrMaxBookings = errors.New("the goal reached its maximum of bookings")
 )
 
-func NewDomain(ctx context.Context, tokener Tokener, username string, notificator Notificator) (*Domain, error) {
-	userID, studentID, err := GetUserIDStudentID(ctx, tokener, username)
+func NewDomain(ctx context.Context, gql *schoolgql.Client, tokener Tokener, username string, notificator Notificator,
+) (*Domain, error) {
+	dom := &Domain{
+		gql:         gql,
+		notificator: notificator,
+		ledger:      NewLedger(0),
+	}
+	dom.tokener = &reloginTokener{Tokener: tokener, dom: dom}
+
+	creds, err := GetCredentials(ctx, gql, dom.tokener, username)
 	if err != nil {
 		return nil, fmt.Errorf("get current user id: %w", err)
 	}
 
-	return &Domain{
-		tokener:     tokener,
-		userID:      userID,
-		studentID:   studentID,
-		notificator: notificator,
-	}, nil
+	dom.userID = creds.UserID
+	dom.studentID = creds.StudentID
+	dom.creds = creds
+
+	return dom, nil
 }
 
-func (dom *Domain) GetCurrentGoals(ctx context.Context) ([]Goal, error) {
-	token, err := dom.tokener.Get(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("tokener get token: %w", err)
-	}
+// SetDryRun makes the domain pretend the bookings succeed without sending them to the platform.
+func (dom *Domain) SetDryRun(dryRun bool) {
+	dom.dryRun = dryRun
+}
 
+// Ledger returns the bookings shared by all the goals.
+func (dom *Domain) Ledger() *Ledger {
+	return dom.ledger
+}
+
+// Flush waits for the pending notifications to be sent.
+func (dom *Domain) Flush() {
+	dom.notifyGroup.Wait()
+}
+
+func (dom *Domain) GetCurrentGoals(ctx context.Context) ([]Goal, error) {
 	req, err := schoolgql.NewRequest(queries.GetStudentCurrentProjects)
 	if err != nil {
 		return nil, fmt.Errorf("new req - get projects: %w", err)
@@ -68,7 +108,7 @@ func (dom *Domain) GetCurrentGoals(ctx context.Context) ([]Goal, error) {
 	req.Variables = queries.VarsGetStudentCurrentProjects{UserID: dom.userID}
 	respProjects := queries.ResponseGetStudentCurrentProjects{}
 
-	err = req.MakeRequest(ctx, token, &respProjects)
+	err = do(ctx, dom.gql, dom.tokener, req, &respProjects)
 	if err != nil {
 		return nil, fmt.Errorf("make request - get projects: %w", err)
 	}
@@ -77,7 +117,7 @@ func (dom *Domain) GetCurrentGoals(ctx context.Context) ([]Goal, error) {
 
 	for _, project := range respProjects.Data.Student.GetStudentCurrentProjects {
 		if project.GoalStatus != nil && *project.GoalStatus != ProjectStatusUnavailable {
-			result = append(result, Goal{GoalID: project.GoalID, Status: *project.GoalStatus})
+			result = append(result, Goal{GoalID: project.GoalID, Name: project.Name, Status: *project.GoalStatus})
 		}
 
 		if project.LocalCourseID != nil {
@@ -108,11 +148,6 @@ func GoalsFilterEvaluated(g []Goal) []Goal {
 }
 
 func (dom *Domain) GetCourseCurrentGoals(ctx context.Context, courseID int) ([]Goal, error) {
-	token, err := dom.tokener.Get(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("tokener get token: %w", err)
-	}
-
 	req, err := schoolgql.NewRequest(queries.GetLocalCourseGoals)
 	if err != nil {
 		return nil, fmt.Errorf("new req - get projects: %w", err)
@@ -121,7 +156,7 @@ func (dom *Domain) GetCourseCurrentGoals(ctx context.Context, courseID int) ([]G
 	req.Variables = queries.VarsGetLocalCourseGoals{LocalCourseID: courseID}
 	respProjects := queries.ResponseGetLocalCourseGoals{}
 
-	err = req.MakeRequest(ctx, token, &respProjects)
+	err = do(ctx, dom.gql, dom.tokener, req, &respProjects)
 	if err != nil {
 		return nil, fmt.Errorf("make request - get projects: %w", err)
 	}
@@ -153,12 +188,12 @@ func (dom *Domain) GetCourseCurrentGoals(ctx context.Context, courseID int) ([]G
 }
 
 func (dom *Domain) GetTaskIDAnswerID(ctx context.Context, goalID int) (string, string, error) {
-	taskID, err := GetTaskIDByGoalID(ctx, dom.tokener, goalID, dom.studentID)
+	taskID, err := GetTaskIDByGoalID(ctx, dom.gql, dom.tokener, goalID, dom.studentID)
 	if err != nil {
 		return "", "", fmt.Errorf("get task id: %w", err)
 	}
 
-	answerID, err := GetAnswerIDByGoalID(ctx, dom.tokener, goalID, dom.studentID)
+	answerID, err := GetAnswerIDByGoalID(ctx, dom.gql, dom.tokener, goalID, dom.studentID)
 	if err != nil {
 		return "", "", fmt.Errorf("get task id: %w", err)
 	}
@@ -166,186 +201,268 @@ func (dom *Domain) GetTaskIDAnswerID(ctx context.Context, goalID int) (string, s
 	return taskID, answerID, nil
 }
 
-func (dom *Domain) AttemptSubscribe(ctx context.Context, taskID, answerID string, ranges [][2]time.Time, online bool,
-) (time.Time, bool, error) {
-	slots, err := GetSlotsRanges(ctx, dom.tokener, taskID, ranges)
-	if err != nil {
-		return time.Time{}, false, fmt.Errorf("get slots from the ranges: %w", err)
-	}
+// SubscribeParams describe what AttemptSubscribe should look for.
+type SubscribeParams struct {
+	Goal   Goal
+	TaskID string
+	Ranges [][2]time.Time
+	// RangeRules is the index of the configured rule per range, the range index is used if nil.
+	RangeRules []int
+	Blackouts  [][2]time.Time // busy time the whole review must not overlap.
+	Mode       BookingMode
+	Ranking    SlotRanking
+	Priority   int  // the goals with the higher priority are planned first, see Plan.
+	Staff      bool // include the slots of the staff, only the peer ones are booked otherwise.
+	// MaxBookings limits the upcoming reviews of the goal in the ledger, zero means no limit.
+	MaxBookings int
+	// Notificator overrides the domain one for the goal when set.
+	Notificator Notificator
+}
 
-	if len(slots) == 0 {
-		return time.Time{}, false, nil
+// AttemptSubscribe occupies the best ranked available slot for the active answer of the goal. Nothing is
+// booked while no review is left to schedule, see ReviewsProgress.Unscheduled, ErrFullyScheduled is returned.
+// The slots too close to the reviews booked by the other goals are skipped, see Ledger.
+func (dom *Domain) AttemptSubscribe(ctx context.Context, params SubscribeParams) (Booking, bool, error) {
+	candidates, err := dom.GetCandidates(ctx, params)
+	if err != nil {
+		return Booking{}, false, err
 	}
 
-	log.Printf("Found %d slots\n", len(slots))
-
-	asyncNotify := func(slotStart time.Time) {
-		if dom.notificator == nil {
-			return
+	for _, slot := range candidates.Slots {
+		if ctx.Err() != nil {
+			return Booking{}, false, fmt.Errorf("occupy: %w", ctx.Err())
 		}
 
-		botCtx, botCtxCancel := context.WithTimeout(ctx, 10*time.Second)
-		defer botCtxCancel()
-
-		botErr := dom.notificator.SendMessage(
-			botCtx, fmt.Sprintf("slot occupied at %s", slotStart.Local().Format(time.DateTime)))
-		if botErr != nil {
-			log.Println("SendMessage:", err.Error())
+		booking, err := dom.book(ctx, candidates.planned(slot))
+		if errors.Is(err, ErrSlotTaken) {
+			continue // another goal has a review too close.
 		}
-	}
 
-	for _, start := range slots {
-		_, err = OccupySlot(ctx, dom.tokener, answerID, start, online)
 		if err == nil {
-			go asyncNotify(start)
+			return booking, true, nil
+		}
 
-			return start, true, nil
+		if !rejected(err) {
+			// the slot might be booked, another one would make a second review.
+			return Booking{}, false, fmt.Errorf("occupy: %w", err)
 		}
 
 		log.Println("Occupy:", err.Error())
 	}
 
-	return time.Time{}, false, nil
+	return Booking{}, false, nil
 }
 
-func GetSlotsRanges(ctx context.Context, tokener Tokener, taskID string, ranges [][2]time.Time) ([]time.Time, error) {
-	numWorkers := len(ranges)
+// book occupies the slot if the ledger allows it, and notifies about the booking. The slot is
+// retried with the other mode when the mode allows the fallback and the platform refused the
+// booking. Any other error leaves it unknown whether the slot got booked, so the slot is neither
+// retried nor released in the ledger, and the error is returned.
+func (dom *Domain) book(ctx context.Context, planned PlannedSlot) (Booking, error) {
+	slot := planned.Slot
 
-	rangesChan := make(chan [2]time.Time)
-	slotsChan := make(chan time.Time)
-	errChan := make(chan error, numWorkers)
-	group := sync.WaitGroup{}
+	key, ok := dom.ledger.Reserve(slot.Start, slot.End())
+	if !ok {
+		return Booking{}, ErrSlotTaken
+	}
 
-	for range numWorkers {
-		group.Add(1)
+	var (
+		bookingID string
+		online    bool
+		errs      []error
+	)
 
-		go func() {
-			defer group.Done()
+	for _, online = range planned.Mode.Attempts() {
+		if dom.dryRun {
+			break
+		}
 
-			for timeRange := range rangesChan {
-				slots, err := GetSlots(ctx, tokener, taskID, timeRange[0], timeRange[1])
-				if errors.Is(err, ErrNoSlots) {
-					return
-				}
+		var err error
 
-				if err != nil {
-					errChan <- fmt.Errorf("get slots: %w", err)
+		bookingID, err = dom.occupySlotDetached(ctx, planned.AnswerID, slot, online)
+		if err == nil {
+			break
+		}
 
-					return
-				}
+		errs = append(errs, fmt.Errorf("%s: %w", onlineName(online), err))
 
-				for _, slot := range slots {
-					slotsChan <- slot
-				}
-			}
-		}()
+		if !rejected(err) {
+			return Booking{}, errors.Join(errs...)
+		}
 	}
 
-	go func() {
-		for _, r := range ranges {
-			rangesChan <- r
-		}
+	if len(errs) == len(planned.Mode.Attempts()) {
+		dom.ledger.Release(key)
 
-		close(rangesChan)
-	}()
+		return Booking{}, errors.Join(errs...)
+	}
 
-	slots := make([]time.Time, 0)
-	collected := make(chan struct{})
-	// collector
-	go func() {
-		for slot := range slotsChan {
-			slots = append(slots, slot)
-		}
+	booking := Booking{
+		ID:       bookingID,
+		Goal:     planned.Goal,
+		Start:    slot.Start,
+		Duration: slot.Duration,
+		Online:   online,
+		DryRun:   dom.dryRun,
+	}
 
-		close(collected)
-	}()
+	dom.ledger.Confirm(key, booking)
+	dom.asyncNotify(ctx, planned.notificator, booking.String())
 
-	group.Wait()
-	close(slotsChan)
-	close(errChan)
+	return booking, nil
+}
+
+// rejected tells whether the platform refused the booking, so that the slot is surely not booked.
+func rejected(err error) bool {
+	var platformErr *myerrs.PlatformError
+
+	return errors.As(err, &platformErr)
+}
 
-	<-collected
+// occupySlotDetached lets the booking mutation finish even if ctx gets cancelled meanwhile,
+// so that a shutdown does not interrupt it mid-request.
+func (dom *Domain) occupySlotDetached(ctx context.Context, answerID string, slot Slot, isOnline bool,
+) (string, error) {
+	occupyCtx, occupyCtxCancel := context.WithTimeout(context.WithoutCancel(ctx), bookingTimeout)
+	defer occupyCtxCancel()
 
-	errs := make([]error, 0, len(errChan))
+	return OccupySlot(occupyCtx, dom.gql, dom.tokener, answerID, slot.Start, isOnline, slot.Staff)
+}
 
-	for err := range errChan {
-		errs = append(errs, err)
+// asyncNotify sends the message with the notificator, or with the domain one if it is nil.
+func (dom *Domain) asyncNotify(ctx context.Context, notificator Notificator, msg string) {
+	if notificator == nil {
+		notificator = dom.notificator
 	}
 
-	err := errors.Join(errs...)
-	if err != nil {
-		return nil, fmt.Errorf("collect slots: %w", err)
+	if notificator == nil {
+		return
 	}
 
-	slices.SortFunc(slots, func(a, b time.Time) int { return int(a.Unix() - b.Unix()) })
+	dom.notifyGroup.Add(1)
+
+	go func() {
+		defer dom.notifyGroup.Done()
+
+		botCtx, botCtxCancel := context.WithTimeout(context.WithoutCancel(ctx), notifyTimeout)
+		defer botCtxCancel()
 
-	return slots, nil
+		botErr := notificator.SendMessage(botCtx, msg)
+		if botErr != nil {
+			log.Println("SendMessage:", botErr.Error())
+		}
+	}()
 }
 
-func GetUserIDStudentID(ctx context.Context, tokener Tokener, username string) (string, string, error) {
-	token, err := tokener.Get(ctx)
-	if err != nil {
-		return "", "", fmt.Errorf("tokener get token: %w", err)
+// GetSlotsRanges collects the slots whose whole review fits in one of the ranges. The slots of the span
+// covering all the ranges are requested at once and attributed to the first range they fit in, see
+// attributeSlots.
+func GetSlotsRanges(ctx context.Context, gql *schoolgql.Client, tokener Tokener, taskID string, ranges [][2]time.Time,
+	rules []int,
+) ([]Slot, error) {
+	if len(ranges) < 1 {
+		return nil, nil
 	}
 
-	req, err := schoolgql.NewRequest(queries.GetCredentialsByLogin)
-	if err != nil {
-		return "", "", fmt.Errorf("new req get credentials: %w", err)
-	}
+	from, to := ranges[0][0], ranges[0][1]
 
-	req.Variables = queries.VarsGetCredentialsByLogin{Login: username}
-	respCreds := queries.ResponseGetCredentialsByLogin{}
+	for _, r := range ranges[1:] {
+		if r[0].Before(from) {
+			from = r[0]
+		}
 
-	err = req.MakeRequest(ctx, token, &respCreds)
-	if err != nil {
-		return "", "", fmt.Errorf("new req get credentials: %w", err)
+		if r[1].After(to) {
+			to = r[1]
+		}
 	}
 
-	return respCreds.Data.School21.GetStudentByLogin.UserID, respCreds.Data.School21.GetStudentByLogin.StudentID, nil
-}
-
-func GetAnswerIDByGoalID(ctx context.Context, tokener Tokener, goalID int, studentID string) (string, error) {
-	token, err := tokener.Get(ctx)
-	if err != nil {
-		return "", fmt.Errorf("tokener get token: %w", err)
+	slots, err := GetSlots(ctx, gql, tokener, taskID, from, to)
+	if errors.Is(err, ErrNoSlots) {
+		return nil, nil
 	}
 
-	req, err := schoolgql.NewRequest(queries.GetProjectAttemptEvaluationsInfoByStudent)
 	if err != nil {
-		return "", fmt.Errorf("new req get attempts: %w", err)
+		return nil, fmt.Errorf("get slots: %w", err)
 	}
 
-	req.Variables = queries.VarsGetProjectAttemptEvaluationsInfoByStudent{GoalID: goalID, StudentID: studentID}
-	resp := queries.ResponseGetProjectAttemptEvaluationsInfoByStudent{}
+	return attributeSlots(slots, ranges, rules), nil
+}
 
-	err = req.MakeRequest(ctx, token, &resp)
-	if err != nil {
-		return "", fmt.Errorf("make req get attempts: %w", err)
-	}
+// attributeSlots keeps the slots fitting in the ranges, sorted by the start. The RangeIndex of a slot is
+// the rule of the first range it fits in, so all the days of a weekly rule share the rule priority. The
+// range index is used when the rules are nil.
+func attributeSlots(slots []Slot, ranges [][2]time.Time, rules []int) []Slot {
+	result := make([]Slot, 0, len(slots))
 
-	answerID := ""
+	for _, slot := range slots {
+		for i, r := range ranges {
+			if !slot.Within(r[0], r[1]) {
+				continue
+			}
 
-	for _, attempt := range resp.Data.School21.GetProjectAttemptEvaluationsInfo {
-		if attempt.AttemptResult == nil {
-			answerID = attempt.StudentAnswerID
+			slot.RangeIndex = i
+			if i < len(rules) {
+				slot.RangeIndex = rules[i]
+			}
+
+			result = append(result, slot)
 
 			break
 		}
 	}
 
-	if answerID == "" {
-		return "", ErrNoAnswers
-	}
+	slices.SortFunc(result, func(a, b Slot) int { return a.Start.Compare(b.Start) })
 
-	return answerID, nil
+	return result
 }
 
-func GetTaskIDByGoalID(ctx context.Context, tokener Tokener, goalID int, studentID string) (string, error) {
-	token, err := tokener.Get(ctx)
+// Credentials identify the user on the platform.
+type Credentials struct {
+	Login     string
+	UserID    string
+	StudentID string
+	SchoolID  string
+}
+
+func GetUserIDStudentID(ctx context.Context, gql *schoolgql.Client, tokener Tokener, username string,
+) (string, string, error) {
+	creds, err := GetCredentials(ctx, gql, tokener, username)
+
+	return creds.UserID, creds.StudentID, err
+}
+
+func GetCredentials(ctx context.Context, gql *schoolgql.Client, tokener Tokener, username string) (Credentials, error) {
+	req, err := schoolgql.NewRequest(queries.GetCredentialsByLogin)
 	if err != nil {
-		return "", fmt.Errorf("tokener get token: %w", err)
+		return Credentials{}, fmt.Errorf("new req get credentials: %w", err)
 	}
 
+	req.Variables = queries.VarsGetCredentialsByLogin{Login: username}
+	respCreds := queries.ResponseGetCredentialsByLogin{}
+
+	err = do(ctx, gql, tokener, req, &respCreds)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("new req get credentials: %w", err)
+	}
+
+	student := respCreds.Data.School21.GetStudentByLogin
+
+	return Credentials{
+		Login:     username,
+		UserID:    student.UserID,
+		StudentID: student.StudentID,
+		SchoolID:  student.SchoolID,
+	}, nil
+}
+
+func GetAnswerIDByGoalID(ctx context.Context, gql *schoolgql.Client, tokener Tokener, goalID int, studentID string,
+) (string, error) {
+	answer, err := GetAnswerEvaluationsByGoalID(ctx, gql, tokener, goalID, studentID)
+
+	return answer.AnswerID, err
+}
+
+func GetTaskIDByGoalID(ctx context.Context, gql *schoolgql.Client, tokener Tokener, goalID int, studentID string,
+) (string, error) {
 	req, err := schoolgql.NewRequest(queries.GetProjectInfoByStudent)
 	if err != nil {
 		return "", fmt.Errorf("new req get project info: %w", err)
@@ -354,7 +471,7 @@ func GetTaskIDByGoalID(ctx context.Context, tokener Tokener, goalID int, student
 	req.Variables = queries.VarsGetProjectInfoByStudent{GoalID: goalID, StudentID: studentID}
 	resp := queries.ResponseGetProjectInfoByStudent{}
 
-	err = req.MakeRequest(ctx, token, &resp)
+	err = do(ctx, gql, tokener, req, &resp)
 	if err != nil {
 		return "", fmt.Errorf("make req get project info: %w", err)
 	}
@@ -362,12 +479,10 @@ func GetTaskIDByGoalID(ctx context.Context, tokener Tokener, goalID int, student
 	return resp.Data.School21.GetModuleByID.CurrentTask.TaskID, nil
 }
 
-func GetSlots(ctx context.Context, tokener Tokener, taskID string, from, to time.Time) ([]time.Time, error) {
-	token, err := tokener.Get(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("tokener get token: %w", err)
-	}
-
+// GetSlots returns the valid review starts in the range, each lasting the check duration of the task.
+// The starts whose review would outlast the timeslot span are dropped.
+func GetSlots(ctx context.Context, gql *schoolgql.Client, tokener Tokener, taskID string, from, to time.Time,
+) ([]Slot, error) {
 	req, err := schoolgql.NewRequest(queries.CalendarGetNameLessStudentTimeslotsForReview)
 	if err != nil {
 		return nil, fmt.Errorf("new req get timeslots: %w", err)
@@ -380,23 +495,40 @@ func GetSlots(ctx context.Context, tokener Tokener, taskID string, from, to time
 	}
 	resp := queries.ResponseCalendarGetNameLessStudentTimeslotsForReview{}
 
-	err = req.MakeRequest(ctx, token, &resp)
+	err = do(ctx, gql, tokener, req, &resp)
 	if err != nil {
 		return nil, fmt.Errorf("make req get timeslots: %w", err)
 	}
 
-	var startTime time.Time
+	var startTime, spanStart, spanEnd time.Time
+
+	timeslots := resp.Data.Student.GetNameLessStudentTimeslotsForReview
+	duration := time.Duration(timeslots.CheckDuration) * checkDurationUnit
+	result := make([]Slot, 0, len(timeslots.TimeSlots))
+
+	for _, slotSpan := range timeslots.TimeSlots {
+		spanStart, err = schoolgql.FormatStrToTime(slotSpan.Start)
+		if err != nil {
+			return nil, fmt.Errorf("parse span start: %w", err)
+		}
 
-	result := make([]time.Time, 0, len(resp.Data.Student.GetNameLessStudentTimeslotsForReview.TimeSlots))
+		spanEnd, err = schoolgql.FormatStrToTime(slotSpan.End)
+		if err != nil {
+			return nil, fmt.Errorf("parse span end: %w", err)
+		}
 
-	for _, slotSpan := range resp.Data.Student.GetNameLessStudentTimeslotsForReview.TimeSlots {
 		for i := range slotSpan.ValidStartTimes {
 			startTime, err = schoolgql.FormatStrToTime(slotSpan.ValidStartTimes[i])
 			if err != nil {
 				return nil, fmt.Errorf("parse time: %w", err)
 			}
 
-			result = append(result, startTime)
+			slot := Slot{Start: startTime, Duration: duration, Staff: slotSpan.StaffSlot}
+			if !slot.Within(spanStart, spanEnd) {
+				continue
+			}
+
+			result = append(result, slot)
 		}
 	}
 
@@ -407,13 +539,9 @@ func GetSlots(ctx context.Context, tokener Tokener, taskID string, from, to time
 	return result, nil
 }
 
-func OccupySlot(ctx context.Context, tokener Tokener, answerID string, slotStart time.Time, isOnline bool,
+func OccupySlot(ctx context.Context, gql *schoolgql.Client, tokener Tokener, answerID string, slotStart time.Time,
+	isOnline, isStaff bool,
 ) (string, error) {
-	token, err := tokener.Get(ctx)
-	if err != nil {
-		return "", fmt.Errorf("tokener get token: %w", err)
-	}
-
 	req, err := schoolgql.NewRequest(queries.CalendarAddBookingToEventSlot)
 	if err != nil {
 		return "", fmt.Errorf("new req add booking: %w", err)
@@ -423,11 +551,11 @@ func OccupySlot(ctx context.Context, tokener Tokener, answerID string, slotStart
 		StartTime:          schoolgql.FormatTimeToStr(slotStart),
 		AnswerID:           answerID,
 		IsOnline:           isOnline,
-		WasStaffSlotChosen: false,
+		WasStaffSlotChosen: isStaff,
 	}
 	resp := queries.ResponseCalendarAddBookingToEventSlot{}
 
-	err = req.MakeRequest(ctx, token, &resp)
+	err = do(ctx, gql, tokener, req, &resp)
 	if err != nil {
 		return "", fmt.Errorf("make req add booking: %w", err)
 	}
@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+const (
+	hoursPerDay = 24
+	// rangePriorityStep makes the range order dominate the other scores.
+	rangePriorityStep = 1e6
+)
+
+// SlotScorer gives a slot a penalty, the slots with the lowest total penalty are booked first.
+type SlotScorer interface {
+	Score(slot Slot) float64
+}
+
+// SlotScoreFunc adapts a function to the SlotScorer interface.
+type SlotScoreFunc func(slot Slot) float64
+
+func (f SlotScoreFunc) Score(slot Slot) float64 {
+	return f(slot)
+}
+
+// SlotRanking orders the slots by the sum of the scores. The equally scored slots are ordered
+// by the start time, earliest first unless Latest is set. The zero value means "earliest wins".
+type SlotRanking struct {
+	Scorers []SlotScorer
+	Latest  bool
+}
+
+func (r SlotRanking) Score(slot Slot) float64 {
+	var score float64
+
+	for _, scorer := range r.Scorers {
+		score += scorer.Score(slot)
+	}
+
+	return score
+}
+
+// Rank sorts the slots in place, the most preferred first.
+func (r SlotRanking) Rank(slots []Slot) {
+	// the scores follow the slots by the index, a Slot holding a time.Time is no reliable map key.
+	type scoredSlot struct {
+		slot  Slot
+		score float64
+	}
+
+	scored := make([]scoredSlot, len(slots))
+	for i, slot := range slots {
+		scored[i] = scoredSlot{slot: slot, score: r.Score(slot)}
+	}
+
+	slices.SortStableFunc(scored, func(a, b scoredSlot) int {
+		if a.score != b.score {
+			if a.score < b.score {
+				return -1
+			}
+
+			return 1
+		}
+
+		if r.Latest {
+			return b.slot.Start.Compare(a.slot.Start)
+		}
+
+		return a.slot.Start.Compare(b.slot.Start)
+	})
+
+	for i := range scored {
+		slots[i] = scored[i].slot
+	}
+}
+
+// TimeOfDayScorer penalizes a slot by the hours between its start and the preferred time of day
+// of its weekday. The slots on the days without a preferred time get the maximal penalty.
+type TimeOfDayScorer struct {
+	Preferred map[time.Weekday]time.Duration // since midnight.
+	Location  *time.Location
+}
+
+func (s TimeOfDayScorer) Score(slot Slot) float64 {
+	start := slot.Start.In(locationOrLocal(s.Location))
+
+	preferred, ok := s.Preferred[start.Weekday()]
+	if !ok {
+		return hoursPerDay / 2 //nolint:mnd // the farthest time of day.
+	}
+
+	midnight := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+
+	diff := math.Abs(start.Sub(midnight).Hours() - preferred.Hours())
+
+	return min(diff, hoursPerDay-diff)
+}
+
+// RangePriorityScorer prefers the slots from the ranges listed earlier.
+type RangePriorityScorer struct{}
+
+func (RangePriorityScorer) Score(slot Slot) float64 {
+	return float64(slot.RangeIndex) * rangePriorityStep
+}
+
+// WeekdayScorer adds a penalty per weekday of the slot start.
+type WeekdayScorer struct {
+	Weights  map[time.Weekday]float64
+	Location *time.Location
+}
+
+func (s WeekdayScorer) Score(slot Slot) float64 {
+	return s.Weights[slot.Start.In(locationOrLocal(s.Location)).Weekday()]
+}
+
+func locationOrLocal(loc *time.Location) *time.Location {
+	if loc == nil {
+		return time.Local
+	}
+
+	return loc
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/eldarbr/schoolsubscriber/internal/timeranges"
+)
+
+func TestRankWeeklyRulePriority(t *testing.T) {
+	t.Parallel()
+
+	weekdays, err := timeranges.ParseWeekly("Mon-Fri 18:00-23:00", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saturday, err := timeranges.ParseWeekly("Sat 18:00-23:00", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schedule := timeranges.Schedule{
+		Rules:   []timeranges.Rule{weekdays, saturday},
+		MinLead: 0,
+		Horizon: 7 * 24 * time.Hour,
+	}
+	now := time.Date(2026, time.March, 2, 12, 0, 0, 0, time.UTC) // Monday.
+	ranges, rules := schedule.Windows(now)
+
+	at := func(day, hour int) Slot {
+		return Slot{Start: time.Date(2026, time.March, day, hour, 0, 0, 0, time.UTC), Duration: time.Hour}
+	}
+
+	slots := attributeSlots([]Slot{at(2, 18), at(3, 18), at(5, 19), at(7, 19), at(8, 19)}, ranges, rules)
+
+	ranking := SlotRanking{Scorers: []SlotScorer{
+		RangePriorityScorer{},
+		TimeOfDayScorer{Preferred: map[time.Weekday]time.Duration{
+			time.Monday: 19 * time.Hour, time.Tuesday: 19 * time.Hour, time.Thursday: 19 * time.Hour,
+			time.Saturday: 19 * time.Hour,
+		}, Location: time.UTC},
+	}}
+	ranking.Rank(slots)
+
+	// the Thursday slot at the preferred time beats the earlier days of the same rule, the
+	// Saturday rule comes last, and the Sunday slot is out of the ranges.
+	want := []Slot{at(5, 19), at(2, 18), at(3, 18), at(7, 19)}
+	want[3].RangeIndex = 1
+
+	if !slices.EqualFunc(slots, want, func(a, b Slot) bool {
+		return a.Start.Equal(b.Start) && a.RangeIndex == b.RangeIndex
+	}) {
+		t.Fatalf("Rank() = %v, want %v", slots, want)
+	}
+}
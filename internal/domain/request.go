@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"github.com/eldarbr/schoolsubscriber/internal/myerrs"
+	"github.com/eldarbr/schoolsubscriber/internal/schoolgql"
+)
+
+// do sends the request with the token of the tokener. When the platform rejects the token, it is
+// invalidated and the request is sent once more with a fresh one. The concurrent requests rejected
+// with the same token share a single login, see Tokener.
+func do(ctx context.Context, gql *schoolgql.Client, tokener Tokener, req *schoolgql.Request,
+	resultPlaceholder schoolgql.IBaseResponse,
+) error {
+	token, err := tokener.Get(ctx)
+	if err != nil {
+		return fmt.Errorf("tokener get token: %w", err)
+	}
+
+	err = gql.Do(ctx, req, token, resultPlaceholder)
+
+	var authErr *myerrs.AuthError
+	if !errors.As(err, &authErr) {
+		return err //nolint:wrapcheck // wrapped by the callers.
+	}
+
+	log.Println("Err", req.OperationName, "token rejected, logging in again:", err)
+
+	invalidateErr := tokener.Invalidate(token)
+	if invalidateErr != nil {
+		return errors.Join(err, fmt.Errorf("tokener invalidate: %w", invalidateErr))
+	}
+
+	token, err = tokener.Get(ctx)
+	if err != nil {
+		return fmt.Errorf("tokener get fresh token: %w", err)
+	}
+
+	return gql.Do(ctx, req, token, resultPlaceholder) //nolint:wrapcheck // wrapped by the callers.
+}
+
+// reloginTokener notifies once when no fresh token can be got after an invalidation.
+type reloginTokener struct {
+	Tokener
+	dom         *Domain
+	invalidated atomic.Bool
+	notified    atomic.Bool
+}
+
+func (t *reloginTokener) Invalidate(token string) error {
+	t.invalidated.Store(true)
+
+	return t.Tokener.Invalidate(token) //nolint:wrapcheck // transparent.
+}
+
+func (t *reloginTokener) Get(ctx context.Context) (string, error) {
+	token, err := t.Tokener.Get(ctx)
+	if err == nil {
+		if t.invalidated.Swap(false) {
+			t.notified.Store(false)
+			log.Println("logged in again")
+		}
+
+		return token, nil
+	}
+
+	if !t.invalidated.Load() {
+		return "", err //nolint:wrapcheck // transparent.
+	}
+
+	if ctx.Err() == nil && !t.notified.Swap(true) {
+		t.dom.asyncNotify(ctx, nil, "Could not log in again, the search is stuck: "+err.Error())
+	}
+
+	return "", err //nolint:wrapcheck // transparent.
+}
@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/eldarbr/schoolsubscriber/internal/schoolgql"
+	"github.com/eldarbr/schoolsubscriber/internal/schoolgql/queries"
+)
+
+// countingTokener logs in by numbering the tokens, the rejected token is only dropped while current.
+type countingTokener struct {
+	mutex   sync.Mutex
+	current string
+	logins  int
+}
+
+func (t *countingTokener) Get(context.Context) (string, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.current == "" {
+		t.logins++
+		t.current = fmt.Sprint("token-", t.logins)
+	}
+
+	return t.current, nil
+}
+
+func (t *countingTokener) Invalidate(token string) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.current == token {
+		t.current = ""
+	}
+
+	return nil
+}
+
+type emptyResponse struct{}
+
+func (*emptyResponse) GetErrorText() string {
+	return ""
+}
+
+func TestDoConcurrentRelogin(t *testing.T) {
+	t.Parallel()
+
+	const workers = 8
+
+	var (
+		rejected atomic.Int32
+		arrived  sync.WaitGroup
+	)
+
+	arrived.Add(workers)
+
+	// the first token is rejected once all the workers have sent it.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Bearer token-1" {
+			rejected.Add(1)
+			arrived.Done()
+			arrived.Wait()
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		fmt.Fprint(w, `{}`)
+	}))
+	defer server.Close()
+
+	gql := schoolgql.NewClient(server.Client(), server.URL)
+	gql.SetEduContext(server.URL, schoolgql.EduContext{SchoolID: "s", ProductID: "p", OrgUnitID: "o"})
+
+	req, err := schoolgql.NewRequest(queries.CalendarGetNameLessStudentTimeslotsForReview)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokener := &countingTokener{mutex: sync.Mutex{}, current: "token-1", logins: 1}
+
+	var (
+		wait sync.WaitGroup
+		errs = make([]error, workers)
+	)
+
+	for i := range workers {
+		wait.Add(1)
+
+		go func() {
+			defer wait.Done()
+
+			errs[i] = do(context.Background(), gql, tokener, req, &emptyResponse{})
+		}()
+	}
+
+	wait.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("worker %d: %v", i, err)
+		}
+	}
+
+	if rejected.Load() != workers {
+		t.Fatalf("rejected %d requests, want %d", rejected.Load(), workers)
+	}
+
+	if tokener.logins != 2 {
+		t.Fatalf("logged in %d times, want one login after the initial one", tokener.logins)
+	}
+}
@@ -0,0 +1,227 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eldarbr/schoolsubscriber/internal/schoolgql"
+	"github.com/eldarbr/schoolsubscriber/internal/schoolgql/queries"
+)
+
+const (
+	// reviewsInfoWindow is the timeslots window requested only to read the reviews info.
+	reviewsInfoWindow = time.Hour
+	// scheduledReviewDuration is assumed for a scheduled review the platform gives no end time for.
+	scheduledReviewDuration = time.Hour
+)
+
+// P2PEvaluation is a single peer review of an answer.
+type P2PEvaluation struct {
+	Status string `json:"status"`
+	// Reviewer and the check times are known once the review is booked.
+	Reviewer   string     `json:"reviewer,omitempty"`
+	StartTime  *time.Time `json:"start_time,omitempty"`
+	FinishTime *time.Time `json:"finish_time,omitempty"`
+}
+
+// AnswerEvaluations lists the P2P evaluations of the answer being evaluated.
+type AnswerEvaluations struct {
+	AnswerID string
+	P2P      []P2PEvaluation
+}
+
+// NotScheduled returns the number of the P2P evaluations nobody is booked for yet.
+func (a AnswerEvaluations) NotScheduled() int {
+	result := 0
+
+	for _, p2p := range a.P2P {
+		if p2p.Status == AnswerStatusNotScheduled {
+			result++
+		}
+	}
+
+	return result
+}
+
+// Scheduled returns the reviews of the answer booked on the platform, by anybody.
+func (a AnswerEvaluations) Scheduled(goal Goal) []Booking {
+	var result []Booking
+
+	for _, p2p := range a.P2P {
+		if p2p.StartTime == nil {
+			continue
+		}
+
+		duration := scheduledReviewDuration
+		if p2p.FinishTime != nil && p2p.FinishTime.After(*p2p.StartTime) {
+			duration = p2p.FinishTime.Sub(*p2p.StartTime)
+		}
+
+		result = append(result, Booking{Goal: goal, Start: *p2p.StartTime, Duration: duration})
+	}
+
+	return result
+}
+
+// ReviewsProgress describes how far the peer reviews of the active answer are scheduled.
+type ReviewsProgress struct {
+	Required    int // reviewByStudentCount.
+	Relevant    int // relevantReviewByStudentsCount.
+	Evaluations AnswerEvaluations
+}
+
+// Unscheduled returns the number of the peer reviews still to be booked, the slots are only booked
+// while it is positive. The NOT_SCHEDULED P2P evaluations are counted, the counters are used if the
+// platform lists no P2P entries yet.
+func (p ReviewsProgress) Unscheduled() int {
+	if len(p.Evaluations.P2P) > 0 {
+		return p.Evaluations.NotScheduled()
+	}
+
+	return max(0, p.Required-p.Relevant)
+}
+
+func (dom *Domain) GetReviewsProgress(ctx context.Context, goalID int, taskID string) (ReviewsProgress, error) {
+	progress, err := GetReviewsInfo(ctx, dom.gql, dom.tokener, taskID)
+	if err != nil {
+		return ReviewsProgress{}, fmt.Errorf("get reviews info: %w", err)
+	}
+
+	progress.Evaluations, err = dom.GetAnswerEvaluations(ctx, goalID)
+	if err != nil {
+		return ReviewsProgress{}, fmt.Errorf("get answer evaluations: %w", err)
+	}
+
+	return progress, nil
+}
+
+// SeedLedger adds the upcoming reviews of the goal scheduled on the platform to the ledger, so that
+// the reviews booked by hand or by another instance count for the buffer and the max bookings.
+func (dom *Domain) SeedLedger(ctx context.Context, goal Goal) error {
+	answer, err := dom.GetAnswerEvaluations(ctx, goal.GoalID)
+	if errors.Is(err, ErrNoAnswers) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("get answer evaluations: %w", err)
+	}
+
+	now := time.Now()
+
+	for _, booking := range answer.Scheduled(goal) {
+		if booking.End().After(now) {
+			dom.ledger.Add(booking)
+		}
+	}
+
+	return nil
+}
+
+func (dom *Domain) GetAnswerEvaluations(ctx context.Context, goalID int) (AnswerEvaluations, error) {
+	return GetAnswerEvaluationsByGoalID(ctx, dom.gql, dom.tokener, goalID, dom.studentID)
+}
+
+// GetReviewsInfo reads the reviews counters, which come along with the task timeslots.
+func GetReviewsInfo(ctx context.Context, gql *schoolgql.Client, tokener Tokener, taskID string,
+) (ReviewsProgress, error) {
+	req, err := schoolgql.NewRequest(queries.CalendarGetNameLessStudentTimeslotsForReview)
+	if err != nil {
+		return ReviewsProgress{}, fmt.Errorf("new req get timeslots: %w", err)
+	}
+
+	now := time.Now()
+	req.Variables = queries.VarsCalendarGetNameLessStudentTimeslotsForReview{
+		TaskID: taskID,
+		From:   schoolgql.FormatTimeToStr(now),
+		To:     schoolgql.FormatTimeToStr(now.Add(reviewsInfoWindow)),
+	}
+	resp := queries.ResponseCalendarGetNameLessStudentTimeslotsForReview{}
+
+	err = do(ctx, gql, tokener, req, &resp)
+	if err != nil {
+		return ReviewsProgress{}, fmt.Errorf("make req get timeslots: %w", err)
+	}
+
+	info := resp.Data.Student.GetNameLessStudentTimeslotsForReview.ProjectReviewsInfo
+
+	return ReviewsProgress{
+		Required: info.ReviewByStudentCount,
+		Relevant: info.RelevantReviewByStudentsCount,
+	}, nil
+}
+
+// GetAnswerEvaluationsByGoalID returns the answer being evaluated with its P2P evaluations. An answer
+// without the attempt result is active; the one having unscheduled evaluations is preferred.
+func GetAnswerEvaluationsByGoalID(ctx context.Context, gql *schoolgql.Client, tokener Tokener, goalID int,
+	studentID string,
+) (AnswerEvaluations, error) {
+	req, err := schoolgql.NewRequest(queries.GetProjectAttemptEvaluationsInfoByStudent)
+	if err != nil {
+		return AnswerEvaluations{}, fmt.Errorf("new req get attempts: %w", err)
+	}
+
+	req.Variables = queries.VarsGetProjectAttemptEvaluationsInfoByStudent{GoalID: goalID, StudentID: studentID}
+	resp := queries.ResponseGetProjectAttemptEvaluationsInfoByStudent{}
+
+	err = do(ctx, gql, tokener, req, &resp)
+	if err != nil {
+		return AnswerEvaluations{}, fmt.Errorf("make req get attempts: %w", err)
+	}
+
+	var (
+		result AnswerEvaluations
+		found  bool
+	)
+
+	for _, attempt := range resp.Data.School21.GetProjectAttemptEvaluationsInfo {
+		if attempt.AttemptResult != nil {
+			continue
+		}
+
+		answer := AnswerEvaluations{
+			AnswerID: attempt.StudentAnswerID,
+			P2P:      make([]P2PEvaluation, 0, len(attempt.P2P)),
+		}
+
+		for _, p2p := range attempt.P2P {
+			evaluation := P2PEvaluation{Status: p2p.Status}
+
+			if p2p.Checklist != nil {
+				if p2p.Checklist.Reviewer != nil {
+					evaluation.Reviewer = p2p.Checklist.Reviewer.Login
+				}
+
+				evaluation.StartTime = parseOptionalTime(p2p.Checklist.StartTimeCheck)
+				evaluation.FinishTime = parseOptionalTime(p2p.Checklist.EndTimeCheck)
+			}
+
+			answer.P2P = append(answer.P2P, evaluation)
+		}
+
+		if !found || answer.NotScheduled() > 0 && result.NotScheduled() == 0 {
+			result, found = answer, true
+		}
+	}
+
+	if !found {
+		return AnswerEvaluations{}, ErrNoAnswers
+	}
+
+	return result, nil
+}
+
+func parseOptionalTime(str *string) *time.Time {
+	if str == nil {
+		return nil
+	}
+
+	result, err := schoolgql.FormatStrToTime(*str)
+	if err != nil {
+		return nil
+	}
+
+	return &result
+}
@@ -0,0 +1,54 @@
+package domain
+
+import "testing"
+
+func TestReviewsProgressUnscheduled(t *testing.T) {
+	t.Parallel()
+
+	evaluations := func(statuses ...string) AnswerEvaluations {
+		result := AnswerEvaluations{AnswerID: "answer", P2P: nil}
+		for _, status := range statuses {
+			result.P2P = append(result.P2P, P2PEvaluation{Status: status})
+		}
+
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		progress ReviewsProgress
+		want     int
+	}{
+		{
+			name:     "no P2P entries yet, the counters decide",
+			progress: ReviewsProgress{Required: 3, Relevant: 1, Evaluations: evaluations()},
+			want:     2,
+		},
+		{
+			name:     "no P2P entries, the counters are covered",
+			progress: ReviewsProgress{Required: 2, Relevant: 3, Evaluations: evaluations()},
+			want:     0,
+		},
+		{
+			name: "the P2P statuses win over the counters",
+			progress: ReviewsProgress{Required: 3, Relevant: 0,
+				Evaluations: evaluations(AnswerStatusNotScheduled, "SCHEDULED", "SCHEDULED")},
+			want: 1,
+		},
+		{
+			name:     "all the P2P scheduled",
+			progress: ReviewsProgress{Required: 2, Relevant: 0, Evaluations: evaluations("SCHEDULED", "SCHEDULED")},
+			want:     0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := test.progress.Unscheduled(); got != test.want {
+				t.Fatalf("Unscheduled() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	SelectorAll     = "all"
+	SelectorExclude = "!"
+)
+
+var ErrBadSelector = errors.New("malformed goal selector")
+
+// GoalsSelect picks the goals matched by the selectors. A selector is either
+// "all", a goal id or a glob over the goal name. Selectors prefixed with "!"
+// exclude goals; when only exclusions are given, they apply to all the goals.
+func GoalsSelect(goals []Goal, selectors []string) ([]Goal, error) {
+	var (
+		include []string
+		exclude []string
+	)
+
+	for _, sel := range selectors {
+		sel = strings.TrimSpace(sel)
+		if sel == "" {
+			continue
+		}
+
+		if strings.HasPrefix(sel, SelectorExclude) {
+			exclude = append(exclude, strings.TrimSpace(strings.TrimPrefix(sel, SelectorExclude)))
+		} else {
+			include = append(include, sel)
+		}
+	}
+
+	if len(include) == 0 && len(exclude) > 0 {
+		include = append(include, SelectorAll)
+	}
+
+	result := make([]Goal, 0, len(goals))
+
+	for _, goal := range goals {
+		included, err := goalMatchesAny(goal, include)
+		if err != nil {
+			return nil, err
+		}
+
+		excluded, err := goalMatchesAny(goal, exclude)
+		if err != nil {
+			return nil, err
+		}
+
+		if included && !excluded {
+			result = append(result, goal)
+		}
+	}
+
+	return result, nil
+}
+
+// SplitSelectors splits a comma separated list of selectors.
+func SplitSelectors(str string) []string {
+	result := []string{}
+
+	for _, sel := range strings.Split(str, ",") {
+		sel = strings.TrimSpace(sel)
+		if sel != "" {
+			result = append(result, sel)
+		}
+	}
+
+	return result
+}
+
+func goalMatchesAny(goal Goal, selectors []string) (bool, error) {
+	for _, sel := range selectors {
+		ok, err := GoalMatches(goal, sel)
+		if err != nil {
+			return false, err
+		}
+
+		if ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// GoalMatches reports whether a single selector (without the "!" prefix) matches the goal.
+func GoalMatches(goal Goal, selector string) (bool, error) {
+	if selector == "" {
+		return false, fmt.Errorf("%w: empty selector", ErrBadSelector)
+	}
+
+	if strings.EqualFold(selector, SelectorAll) {
+		return true, nil
+	}
+
+	if goalID, err := strconv.Atoi(selector); err == nil {
+		return goal.GoalID == goalID, nil
+	}
+
+	ok, err := path.Match(strings.ToLower(selector), strings.ToLower(goal.Name))
+	if err != nil {
+		return false, fmt.Errorf("%w: %q: %w", ErrBadSelector, selector, err)
+	}
+
+	return ok, nil
+}
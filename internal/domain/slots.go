@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/eldarbr/schoolsubscriber/internal/timeranges"
+)
+
+// Slot is an available review start found in one of the searched ranges.
+type Slot struct {
+	Start      time.Time     `json:"start"`
+	Duration   time.Duration `json:"-"`
+	RangeIndex int           `json:"range_index"` // the configured range rule, see SubscribeParams.RangeRules.
+	Staff      bool          `json:"staff"`       // the slot is offered by the staff rather than a peer.
+}
+
+// End returns when the review is over.
+func (s Slot) End() time.Time {
+	return s.Start.Add(s.Duration)
+}
+
+// Within reports whether the whole review lies inside [from, to].
+func (s Slot) Within(from, to time.Time) bool {
+	return !s.Start.Before(from) && !s.End().After(to)
+}
+
+// Booking is an occupied slot.
+type Booking struct {
+	ID       string        `json:"id"`
+	Goal     Goal          `json:"goal"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"-"`
+	Online   bool          `json:"online"`
+	DryRun   bool          `json:"dry_run"` // the slot was not actually booked, see Domain.SetDryRun.
+}
+
+func (b Booking) End() time.Time {
+	return b.Start.Add(b.Duration)
+}
+
+// ModeName returns "online" or "offline".
+func (b Booking) ModeName() string {
+	return onlineName(b.Online)
+}
+
+func (b Booking) String() string {
+	verb := "occupied"
+	if b.DryRun {
+		verb = "would be occupied (dry run)"
+	}
+
+	return fmt.Sprintf("slot %s at %s for %s (%s, %s)", verb,
+		b.Start.Local().Format(time.DateTime), b.Goal.Name, b.Duration, b.ModeName())
+}
+
+// SlotsFilterStaff drops the staff slots.
+func SlotsFilterStaff(slots []Slot) []Slot {
+	return slices.DeleteFunc(slots, func(slot Slot) bool { return slot.Staff })
+}
+
+// SlotsFilterBusy drops the slots whose review would overlap the busy time.
+func SlotsFilterBusy(slots []Slot, busy [][2]time.Time) []Slot {
+	result := make([]Slot, 0, len(slots))
+
+	for _, slot := range slots {
+		if !timeranges.Overlaps(busy, slot.Start, slot.End()) {
+			result = append(result, slot)
+		}
+	}
+
+	return result
+}
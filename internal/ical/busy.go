@@ -0,0 +1,135 @@
+package ical
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Occurrences returns the event occurrences intersecting [from, to), the exDates are skipped.
+func (e Event) Occurrences(from, to time.Time, exDates []time.Time) [][2]time.Time {
+	duration := e.End.Sub(e.Start)
+	starts := []time.Time{e.Start}
+
+	if e.RRule != nil {
+		starts = e.RRule.Starts(e.Start, from.Add(-duration), to)
+	}
+
+	result := make([][2]time.Time, 0, len(starts))
+
+	for _, start := range starts {
+		end := start.Add(duration)
+		if !start.Before(to) || !end.After(from) {
+			continue
+		}
+
+		if slices.ContainsFunc(exDates, start.Equal) {
+			continue
+		}
+
+		result = append(result, [2]time.Time{start, end})
+	}
+
+	return result
+}
+
+// BusyWindows returns the time blocked by the events within [from, to). The overridden
+// occurrences of the recurring events are replaced by their overrides.
+func BusyWindows(events []Event, from, to time.Time) [][2]time.Time {
+	overridden := make(map[string][]time.Time)
+
+	for _, event := range events {
+		if event.RecurrenceID != nil {
+			overridden[event.UID] = append(overridden[event.UID], *event.RecurrenceID)
+		}
+	}
+
+	result := [][2]time.Time{}
+
+	for _, event := range events {
+		if !event.Busy() {
+			continue
+		}
+
+		exDates := event.ExDates
+		if event.RecurrenceID == nil {
+			exDates = append(slices.Clone(exDates), overridden[event.UID]...)
+		}
+
+		result = append(result, event.Occurrences(from, to, exDates)...)
+	}
+
+	return result
+}
+
+// File is a calendar file read again once it changes. It is a timeranges.Rule of the busy time.
+type File struct {
+	path    string
+	loc     *time.Location
+	mutex   sync.Mutex
+	modTime time.Time
+	size    int64
+	events  []Event
+}
+
+// OpenFile reads the calendar file, the floating times are taken in loc.
+func OpenFile(path string, loc *time.Location) (*File, error) {
+	file := &File{path: path, loc: loc}
+
+	err := file.reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// Windows returns the busy time of the calendar. If the changed file can not be read,
+// the previously read events are used.
+func (f *File) Windows(_, from, to time.Time) [][2]time.Time {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	err := f.reload()
+	if err != nil {
+		log.Println("Err Reload calendar:", err)
+	}
+
+	return BusyWindows(f.events, from, to)
+}
+
+// reload reads the file if its modification time or size have changed. Must be called with the mutex held.
+func (f *File) reload() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return fmt.Errorf("stat calendar: %w", err)
+	}
+
+	if info.ModTime().Equal(f.modTime) && info.Size() == f.size && f.events != nil {
+		return nil
+	}
+
+	reader, err := os.Open(f.path)
+	if err != nil {
+		return fmt.Errorf("open calendar: %w", err)
+	}
+	defer reader.Close()
+
+	events, err := Parse(reader, f.loc)
+	if err != nil {
+		return fmt.Errorf("parse calendar %s: %w", f.path, err)
+	}
+
+	f.events = events
+	f.modTime = info.ModTime()
+	f.size = info.Size()
+
+	if f.events == nil {
+		f.events = []Event{}
+	}
+
+	return nil
+}
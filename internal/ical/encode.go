@@ -0,0 +1,91 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// maxLineOctets is the RFC 5545 line length limit, the longer lines are folded.
+	maxLineOctets = 75
+	prodID        = "-//schoolsubscriber//EN"
+)
+
+// Encode writes the events as a calendar, the times are written in UTC.
+func Encode(writer io.Writer, name string, events []Event) error {
+	out := bufio.NewWriter(writer)
+
+	lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:" + prodID, "CALSCALE:GREGORIAN"}
+	if name != "" {
+		lines = append(lines, "X-WR-CALNAME:"+escapeText(name))
+	}
+
+	stamp := time.Now().UTC().Format(layoutDateTimeUTC)
+
+	for _, event := range events {
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+event.UID,
+			"DTSTAMP:"+stamp,
+			"DTSTART:"+event.Start.UTC().Format(layoutDateTimeUTC),
+			"DTEND:"+event.End.UTC().Format(layoutDateTimeUTC),
+			"SUMMARY:"+escapeText(event.Summary),
+		)
+
+		if event.Description != "" {
+			lines = append(lines, "DESCRIPTION:"+escapeText(event.Description))
+		}
+
+		for _, key := range slices.Sorted(maps.Keys(event.Extra)) {
+			lines = append(lines, key+":"+escapeText(event.Extra[key]))
+		}
+
+		lines = append(lines, "END:VEVENT")
+	}
+
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		_, err := out.WriteString(fold(line) + "\r\n")
+		if err != nil {
+			return fmt.Errorf("write calendar: %w", err)
+		}
+	}
+
+	err := out.Flush()
+	if err != nil {
+		return fmt.Errorf("flush calendar: %w", err)
+	}
+
+	return nil
+}
+
+func escapeText(str string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(str)
+}
+
+// fold splits the line into the chunks of at most maxLineOctets, not breaking the UTF-8 sequences.
+func fold(line string) string {
+	builder := strings.Builder{}
+	size := 0
+
+	for _, char := range line {
+		charSize := len(string(char))
+		if size+charSize > maxLineOctets {
+			builder.WriteString("\r\n ")
+
+			size = 1
+		}
+
+		builder.WriteRune(char)
+
+		size += charSize
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,180 @@
+package ical
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func utc(str string) time.Time {
+	result, err := time.Parse(time.DateTime, str)
+	if err != nil {
+		panic(err)
+	}
+
+	return result
+}
+
+func calendar(events ...string) string {
+	var builder strings.Builder
+
+	builder.WriteString("BEGIN:VCALENDAR\r\nVERSION:2.0\r\n")
+
+	for _, event := range events {
+		builder.WriteString("BEGIN:VEVENT\r\n" + strings.ReplaceAll(strings.TrimSpace(event), "\n", "\r\n") +
+			"\r\nEND:VEVENT\r\n")
+	}
+
+	builder.WriteString("END:VCALENDAR\r\n")
+
+	return builder.String()
+}
+
+func TestBusyWindows(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		calendar string
+		from, to time.Time
+		want     [][2]time.Time
+	}{
+		{
+			name: "TZID keeps the wall clock across DST",
+			calendar: calendar(`UID:dst
+DTSTART;TZID=Europe/Berlin:20260322T100000
+DTEND;TZID=Europe/Berlin:20260322T110000
+RRULE:FREQ=WEEKLY;COUNT=3`),
+			from: utc("2026-03-01 00:00:00"), to: utc("2026-05-01 00:00:00"),
+			want: [][2]time.Time{
+				{utc("2026-03-22 09:00:00"), utc("2026-03-22 10:00:00")},
+				{utc("2026-03-29 08:00:00"), utc("2026-03-29 09:00:00")},
+				{utc("2026-04-05 08:00:00"), utc("2026-04-05 09:00:00")},
+			},
+		},
+		{
+			name: "VALUE=DATE lasts the whole day",
+			calendar: calendar(`UID:allday
+DTSTART;VALUE=DATE:20261020`),
+			from: utc("2026-10-01 00:00:00"), to: utc("2026-11-01 00:00:00"),
+			want: [][2]time.Time{{utc("2026-10-20 00:00:00"), utc("2026-10-21 00:00:00")}},
+		},
+		{
+			name: "EXDATE skips an occurrence",
+			calendar: calendar(`UID:exdate
+DTSTART:20261001T100000Z
+DURATION:PT1H
+RRULE:FREQ=DAILY;COUNT=3
+EXDATE:20261002T100000Z`),
+			from: utc("2026-10-01 00:00:00"), to: utc("2026-11-01 00:00:00"),
+			want: [][2]time.Time{
+				{utc("2026-10-01 10:00:00"), utc("2026-10-01 11:00:00")},
+				{utc("2026-10-03 10:00:00"), utc("2026-10-03 11:00:00")},
+			},
+		},
+		{
+			name: "RECURRENCE-ID replaces the occurrence",
+			calendar: calendar(`UID:override
+DTSTART:20261001T100000Z
+DTEND:20261001T110000Z
+RRULE:FREQ=WEEKLY;COUNT=2`, `UID:override
+RECURRENCE-ID:20261008T100000Z
+DTSTART:20261008T150000Z
+DTEND:20261008T170000Z`),
+			from: utc("2026-10-01 00:00:00"), to: utc("2026-11-01 00:00:00"),
+			want: [][2]time.Time{
+				{utc("2026-10-01 10:00:00"), utc("2026-10-01 11:00:00")},
+				{utc("2026-10-08 15:00:00"), utc("2026-10-08 17:00:00")},
+			},
+		},
+		{
+			name: "COUNT limits the occurrences",
+			calendar: calendar(`UID:count
+DTSTART:20261001T100000Z
+DURATION:PT30M
+RRULE:FREQ=DAILY;COUNT=2`),
+			from: utc("2026-10-01 00:00:00"), to: utc("2026-11-01 00:00:00"),
+			want: [][2]time.Time{
+				{utc("2026-10-01 10:00:00"), utc("2026-10-01 10:30:00")},
+				{utc("2026-10-02 10:00:00"), utc("2026-10-02 10:30:00")},
+			},
+		},
+		{
+			name: "UNTIL is inclusive",
+			calendar: calendar(`UID:until
+DTSTART:20261001T100000Z
+DURATION:PT30M
+RRULE:FREQ=DAILY;UNTIL=20261003T100000Z`),
+			from: utc("2026-10-01 00:00:00"), to: utc("2026-11-01 00:00:00"),
+			want: [][2]time.Time{
+				{utc("2026-10-01 10:00:00"), utc("2026-10-01 10:30:00")},
+				{utc("2026-10-02 10:00:00"), utc("2026-10-02 10:30:00")},
+				{utc("2026-10-03 10:00:00"), utc("2026-10-03 10:30:00")},
+			},
+		},
+		{
+			name: "INTERVAL with BYDAY skips the weeks",
+			calendar: calendar(`UID:interval
+DTSTART:20261005T180000Z
+DURATION:PT1H
+RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE`),
+			from: utc("2026-10-01 00:00:00"), to: utc("2026-10-26 00:00:00"),
+			want: [][2]time.Time{
+				{utc("2026-10-05 18:00:00"), utc("2026-10-05 19:00:00")},
+				{utc("2026-10-07 18:00:00"), utc("2026-10-07 19:00:00")},
+				{utc("2026-10-19 18:00:00"), utc("2026-10-19 19:00:00")},
+				{utc("2026-10-21 18:00:00"), utc("2026-10-21 19:00:00")},
+			},
+		},
+		{
+			name: "date UNTIL includes the whole day",
+			calendar: calendar(`UID:until-date
+DTSTART:20261001T180000
+DTEND:20261001T190000
+RRULE:FREQ=DAILY;UNTIL=20261003`),
+			from: utc("2026-10-01 00:00:00"), to: utc("2026-11-01 00:00:00"),
+			want: [][2]time.Time{
+				{utc("2026-10-01 18:00:00"), utc("2026-10-01 19:00:00")},
+				{utc("2026-10-02 18:00:00"), utc("2026-10-02 19:00:00")},
+				{utc("2026-10-03 18:00:00"), utc("2026-10-03 19:00:00")},
+			},
+		},
+		{
+			name: "TRANSP and STATUS free the time",
+			calendar: calendar(`UID:transparent
+DTSTART:20261001T100000Z
+DURATION:PT1H
+TRANSP:TRANSPARENT`, `UID:cancelled
+DTSTART:20261002T100000Z
+DURATION:PT1H
+STATUS:CANCELLED`, `UID:opaque
+DTSTART:20261003T100000Z
+DURATION:PT1H
+TRANSP:OPAQUE
+STATUS:CONFIRMED`),
+			from: utc("2026-10-01 00:00:00"), to: utc("2026-11-01 00:00:00"),
+			want: [][2]time.Time{{utc("2026-10-03 10:00:00"), utc("2026-10-03 11:00:00")}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			events, err := Parse(strings.NewReader(test.calendar), time.UTC)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+
+			got := BusyWindows(events, test.from, test.to)
+			slices.SortFunc(got, func(a, b [2]time.Time) int { return a[0].Compare(b[0]) })
+
+			if !slices.EqualFunc(got, test.want, func(a, b [2]time.Time) bool {
+				return a[0].Equal(b[0]) && a[1].Equal(b[1])
+			}) {
+				t.Errorf("BusyWindows() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
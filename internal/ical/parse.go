@@ -0,0 +1,350 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	layoutDateTimeUTC = "20060102T150405Z"
+	layoutDateTime    = "20060102T150405"
+	layoutDate        = "20060102"
+
+	statusCancelled   = "CANCELLED"
+	transpTransparent = "TRANSPARENT"
+)
+
+var (
+	ErrBadCalendar = errors.New("malformed calendar")
+	ErrBadDuration = errors.New("malformed duration")
+)
+
+// Event is a VEVENT reduced to what matters for the busy time.
+type Event struct {
+	UID          string
+	Summary      string
+	Description  string
+	Start        time.Time
+	End          time.Time
+	AllDay       bool
+	RRule        *RRule
+	ExDates      []time.Time
+	RecurrenceID *time.Time // set on an override of a single occurrence of a recurring event.
+	Cancelled    bool
+	Transparent  bool              // the event does not block the time.
+	Extra        map[string]string // the non-standard X- properties.
+}
+
+// Busy reports whether the event blocks the time.
+func (e Event) Busy() bool {
+	return !e.Cancelled && !e.Transparent
+}
+
+type contentLine struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse reads the VEVENTs of the calendar. The floating times and the all-day events are
+// taken in loc, as well as the times with a TZID unknown to the system.
+func Parse(reader io.Reader, loc *time.Location) ([]Event, error) {
+	lines, err := unfold(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		result  []Event
+		current *Event
+		// depth of the components nested into the event, like VALARM.
+		nested      int
+		hasDuration bool
+		duration    time.Duration
+	)
+
+	for num, raw := range lines {
+		line, err := parseContentLine(raw)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", num+1, err)
+		}
+
+		switch {
+		case line.name == "BEGIN" && strings.EqualFold(line.value, "VEVENT") && current == nil:
+			current, nested, hasDuration, duration = &Event{}, 0, false, 0
+		case current == nil:
+		case line.name == "BEGIN":
+			nested++
+		case line.name == "END" && nested > 0:
+			nested--
+		case line.name == "END" && strings.EqualFold(line.value, "VEVENT"):
+			if current.End.IsZero() {
+				current.End = defaultEnd(*current, hasDuration, duration)
+			}
+
+			if !current.Start.IsZero() {
+				result = append(result, *current)
+			}
+
+			current = nil
+		case nested > 0:
+		default:
+			hasDuration, duration, err = applyProperty(current, line, loc, hasDuration, duration)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", num+1, err)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func applyProperty(event *Event, line contentLine, loc *time.Location, hasDuration bool, duration time.Duration,
+) (bool, time.Duration, error) {
+	var err error
+
+	switch line.name {
+	case "UID":
+		event.UID = line.value
+	case "SUMMARY":
+		event.Summary = unescapeText(line.value)
+	case "DESCRIPTION":
+		event.Description = unescapeText(line.value)
+	case "DTSTART":
+		event.Start, event.AllDay, err = parseTime(line, loc)
+	case "DTEND":
+		event.End, _, err = parseTime(line, loc)
+	case "DURATION":
+		duration, err = ParseDuration(line.value)
+		hasDuration = true
+	case "RRULE":
+		event.RRule, err = ParseRRule(line.value, loc)
+	case "EXDATE":
+		var exDates []time.Time
+
+		exDates, err = parseTimeList(line, loc)
+		event.ExDates = append(event.ExDates, exDates...)
+	case "RECURRENCE-ID":
+		var recurrenceID time.Time
+
+		recurrenceID, _, err = parseTime(line, loc)
+		event.RecurrenceID = &recurrenceID
+	case "STATUS":
+		event.Cancelled = strings.EqualFold(line.value, statusCancelled)
+	case "TRANSP":
+		event.Transparent = strings.EqualFold(line.value, transpTransparent)
+	default:
+		if strings.HasPrefix(line.name, "X-") {
+			if event.Extra == nil {
+				event.Extra = make(map[string]string)
+			}
+
+			event.Extra[line.name] = unescapeText(line.value)
+		}
+	}
+
+	if err != nil {
+		return hasDuration, duration, fmt.Errorf("%s: %w", line.name, err)
+	}
+
+	return hasDuration, duration, nil
+}
+
+// defaultEnd applies DURATION, or the RFC 5545 defaults: an all-day event lasts the day,
+// otherwise the event ends when it starts.
+func defaultEnd(event Event, hasDuration bool, duration time.Duration) time.Time {
+	switch {
+	case hasDuration:
+		return event.Start.Add(duration)
+	case event.AllDay:
+		return event.Start.AddDate(0, 0, 1)
+	default:
+		return event.Start
+	}
+}
+
+// unfold joins the folded lines, dropping the empty ones.
+func unfold(reader io.Reader) ([]string, error) {
+	var result []string
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, bufio.MaxScanTokenSize*16) //nolint:mnd // long descriptions.
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(result) > 0 {
+			result[len(result)-1] += line[1:]
+
+			continue
+		}
+
+		if line != "" {
+			result = append(result, line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read calendar: %w", err)
+	}
+
+	return result, nil
+}
+
+// parseContentLine splits "NAME;PARAM=VALUE;PARAM2="quoted:value":VALUE".
+func parseContentLine(raw string) (contentLine, error) {
+	quoted := false
+	colon := -1
+
+	for i, char := range raw {
+		if char == '"' {
+			quoted = !quoted
+		}
+
+		if char == ':' && !quoted {
+			colon = i
+
+			break
+		}
+	}
+
+	if colon < 0 {
+		return contentLine{}, fmt.Errorf("%w: no value in %q", ErrBadCalendar, raw)
+	}
+
+	parts := strings.Split(raw[:colon], ";")
+	result := contentLine{
+		name:   strings.ToUpper(parts[0]),
+		params: make(map[string]string, len(parts)-1),
+		value:  raw[colon+1:],
+	}
+
+	for _, param := range parts[1:] {
+		key, value, _ := strings.Cut(param, "=")
+		result.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+
+	return result, nil
+}
+
+func unescapeText(str string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(str)
+}
+
+// parseTime returns the time and whether it is a date without the time.
+func parseTime(line contentLine, loc *time.Location) (time.Time, bool, error) {
+	return parseTimeValue(line.value, line.params, loc)
+}
+
+func parseTimeList(line contentLine, loc *time.Location) ([]time.Time, error) {
+	var result []time.Time
+
+	for _, value := range strings.Split(line.value, ",") {
+		parsed, _, err := parseTimeValue(value, line.params, loc)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, parsed)
+	}
+
+	return result, nil
+}
+
+func parseTimeValue(value string, params map[string]string, loc *time.Location) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+
+	if tzid, ok := params["TZID"]; ok {
+		if tzLoc, err := time.LoadLocation(tzid); err == nil {
+			loc = tzLoc
+		}
+	}
+
+	if params["VALUE"] == "DATE" || len(value) == len(layoutDate) {
+		result, err := time.ParseInLocation(layoutDate, value, loc)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("%w: %w", ErrBadCalendar, err)
+		}
+
+		return result, true, nil
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		result, err := time.Parse(layoutDateTimeUTC, value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("%w: %w", ErrBadCalendar, err)
+		}
+
+		return result, false, nil
+	}
+
+	result, err := time.ParseInLocation(layoutDateTime, value, loc)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%w: %w", ErrBadCalendar, err)
+	}
+
+	return result, false, nil
+}
+
+// ParseDuration parses an RFC 5545 duration like "PT1H30M", "P1D" or "-P1W".
+func ParseDuration(str string) (time.Duration, error) {
+	str = strings.ToUpper(strings.TrimSpace(str))
+	sign := time.Duration(1)
+
+	switch {
+	case strings.HasPrefix(str, "-"):
+		sign, str = -1, str[1:]
+	case strings.HasPrefix(str, "+"):
+		str = str[1:]
+	}
+
+	if !strings.HasPrefix(str, "P") || len(str) < 2 { //nolint:mnd // "P" and a value at least.
+		return 0, fmt.Errorf("%w: %q", ErrBadDuration, str)
+	}
+
+	units := map[byte]time.Duration{
+		'W': 7 * 24 * time.Hour, //nolint:mnd // a week.
+		'D': 24 * time.Hour,     //nolint:mnd // a day.
+	}
+	timeUnits := map[byte]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+
+	var (
+		result time.Duration
+		number string
+	)
+
+	for i := 1; i < len(str); i++ {
+		char := str[i]
+
+		switch {
+		case char == 'T':
+			units = timeUnits
+		case char >= '0' && char <= '9':
+			number += string(char)
+		default:
+			unit, ok := units[char]
+			if !ok || number == "" {
+				return 0, fmt.Errorf("%w: %q", ErrBadDuration, str)
+			}
+
+			value, err := strconv.Atoi(number)
+			if err != nil {
+				return 0, fmt.Errorf("%w: %q", ErrBadDuration, str)
+			}
+
+			result += time.Duration(value) * unit
+			number = ""
+		}
+	}
+
+	if number != "" {
+		return 0, fmt.Errorf("%w: %q", ErrBadDuration, str)
+	}
+
+	return sign * result, nil
+}
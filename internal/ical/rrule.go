@@ -0,0 +1,330 @@
+package ical
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+	FreqYearly  = "YEARLY"
+
+	daysPerWeek = 7
+	// maxPeriods bounds the expansion of a rule without COUNT and UNTIL.
+	maxPeriods = 100000
+)
+
+var ErrBadRRule = errors.New("malformed recurrence rule")
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// ByDay is a BYDAY entry, Ordinal is set for the entries like "2TU" or "-1FR".
+type ByDay struct {
+	Ordinal int
+	Weekday time.Weekday
+}
+
+// RRule is the supported subset of the RFC 5545 recurrence rule: FREQ, INTERVAL, COUNT,
+// UNTIL, BYDAY, BYMONTHDAY and BYMONTH. The weeks start on Monday.
+type RRule struct {
+	Freq       string
+	Interval   int
+	Count      int
+	Until      *time.Time
+	ByDay      []ByDay
+	ByMonthDay []int
+	ByMonth    []time.Month
+}
+
+func ParseRRule(str string, loc *time.Location) (*RRule, error) {
+	result := &RRule{Interval: 1}
+
+	for _, part := range strings.Split(str, ";") {
+		key, value, _ := strings.Cut(part, "=")
+
+		err := result.applyPart(strings.ToUpper(key), strings.ToUpper(value), loc)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %w", ErrBadRRule, str, err)
+		}
+	}
+
+	switch result.Freq {
+	case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+	default:
+		return nil, fmt.Errorf("%w: unsupported FREQ %q", ErrBadRRule, result.Freq)
+	}
+
+	if result.Interval < 1 {
+		return nil, fmt.Errorf("%w: INTERVAL should be positive", ErrBadRRule)
+	}
+
+	return result, nil
+}
+
+func (r *RRule) applyPart(key, value string, loc *time.Location) error {
+	var err error
+
+	switch key {
+	case "FREQ":
+		r.Freq = value
+	case "INTERVAL":
+		r.Interval, err = strconv.Atoi(value)
+	case "COUNT":
+		r.Count, err = strconv.Atoi(value)
+	case "UNTIL":
+		var (
+			until    time.Time
+			dateOnly bool
+		)
+
+		until, dateOnly, err = parseTimeValue(value, nil, loc)
+		if dateOnly {
+			// the whole UNTIL day is inclusive, the occurrences starting later that day count too.
+			until = time.Date(until.Year(), until.Month(), until.Day()+1, 0, 0, 0, 0, until.Location()).
+				Add(-time.Nanosecond)
+		}
+
+		r.Until = &until
+	case "BYDAY":
+		for _, code := range strings.Split(value, ",") {
+			var byDay ByDay
+
+			byDay, err = parseByDay(code)
+			if err != nil {
+				return err
+			}
+
+			r.ByDay = append(r.ByDay, byDay)
+		}
+	case "BYMONTHDAY":
+		r.ByMonthDay, err = parseInts(value)
+	case "BYMONTH":
+		var months []int
+
+		months, err = parseInts(value)
+		for _, month := range months {
+			r.ByMonth = append(r.ByMonth, time.Month(month))
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+
+	return nil
+}
+
+func parseByDay(code string) (ByDay, error) {
+	code = strings.TrimSpace(code)
+	if len(code) < 2 { //nolint:mnd // the weekday code.
+		return ByDay{}, fmt.Errorf("%w: BYDAY %q", ErrBadRRule, code)
+	}
+
+	weekday, ok := weekdayCodes[code[len(code)-2:]]
+	if !ok {
+		return ByDay{}, fmt.Errorf("%w: BYDAY %q", ErrBadRRule, code)
+	}
+
+	result := ByDay{Weekday: weekday}
+
+	if ordinal := code[:len(code)-2]; ordinal != "" {
+		var err error
+
+		result.Ordinal, err = strconv.Atoi(ordinal)
+		if err != nil {
+			return ByDay{}, fmt.Errorf("%w: BYDAY %q", ErrBadRRule, code)
+		}
+	}
+
+	return result, nil
+}
+
+func parseInts(value string) ([]int, error) {
+	var result []int
+
+	for _, part := range strings.Split(value, ",") {
+		num, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("parse number: %w", err)
+		}
+
+		result = append(result, num)
+	}
+
+	return result, nil
+}
+
+// Starts returns the occurrence starts of the rule for an event starting at dtstart, up to the
+// one starting before to. The occurrences keep the wall clock of dtstart in its location.
+func (r *RRule) Starts(dtstart, from, to time.Time) []time.Time {
+	var (
+		result []time.Time
+		count  int
+	)
+
+	first := 0
+	if r.Count == 0 {
+		// nothing before from matters without COUNT, skip the periods wholesale.
+		first = max(0, r.periodsBetween(dtstart, from)/r.Interval-1) * r.Interval
+	}
+
+	for period := first; period < first+maxPeriods*r.Interval; period += r.Interval {
+		for _, start := range r.periodStarts(dtstart, period) {
+			if start.Before(dtstart) {
+				continue
+			}
+
+			count++
+
+			if (r.Count > 0 && count > r.Count) || (r.Until != nil && start.After(*r.Until)) || !start.Before(to) {
+				return result
+			}
+
+			if !start.Before(from) {
+				result = append(result, start)
+			}
+		}
+	}
+
+	return result
+}
+
+// periodsBetween counts the whole periods from dtstart till moment.
+func (r *RRule) periodsBetween(dtstart, moment time.Time) int {
+	moment = moment.In(dtstart.Location())
+	days := int(civilDay(moment).Sub(civilDay(dtstart)).Hours() / 24) //nolint:mnd // hours per day.
+
+	switch r.Freq {
+	case FreqDaily:
+		return days
+	case FreqWeekly:
+		return days / daysPerWeek
+	case FreqMonthly:
+		return (moment.Year()-dtstart.Year())*12 + int(moment.Month()-dtstart.Month()) //nolint:mnd // months.
+	default:
+		return moment.Year() - dtstart.Year()
+	}
+}
+
+// periodStarts returns the sorted occurrence candidates of the period with the index.
+func (r *RRule) periodStarts(dtstart time.Time, period int) []time.Time {
+	var days []time.Time
+
+	year, month, day := dtstart.Date()
+	loc := dtstart.Location()
+
+	switch r.Freq {
+	case FreqDaily:
+		days = []time.Time{time.Date(year, month, day+period, 0, 0, 0, 0, loc)}
+	case FreqWeekly:
+		monday := day - (int(dtstart.Weekday())+daysPerWeek-int(time.Monday))%daysPerWeek
+		weekdays := r.ByDay
+		if len(weekdays) == 0 {
+			weekdays = []ByDay{{Weekday: dtstart.Weekday()}}
+		}
+
+		for _, byDay := range weekdays {
+			offset := (int(byDay.Weekday) + daysPerWeek - int(time.Monday)) % daysPerWeek
+			days = append(days, time.Date(year, month, monday+period*daysPerWeek+offset, 0, 0, 0, 0, loc))
+		}
+	case FreqMonthly:
+		days = r.monthDays(dtstart, time.Date(year, month+time.Month(period), 1, 0, 0, 0, 0, loc))
+	default:
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{month}
+		}
+
+		for _, m := range months {
+			days = append(days, r.monthDays(dtstart, time.Date(year+period, m, 1, 0, 0, 0, 0, loc))...)
+		}
+	}
+
+	result := make([]time.Time, 0, len(days))
+
+	for _, d := range days {
+		if !r.dayMatches(d) {
+			continue
+		}
+
+		result = append(result, time.Date(d.Year(), d.Month(), d.Day(),
+			dtstart.Hour(), dtstart.Minute(), dtstart.Second(), 0, loc))
+	}
+
+	slices.SortFunc(result, func(a, b time.Time) int { return a.Compare(b) })
+
+	return slices.CompactFunc(result, func(a, b time.Time) bool { return a.Equal(b) })
+}
+
+// monthDays expands BYMONTHDAY or BYDAY within the month, or repeats the day of dtstart.
+func (r *RRule) monthDays(dtstart, firstDay time.Time) []time.Time {
+	var result []time.Time
+
+	lastDay := firstDay.AddDate(0, 1, -1).Day()
+	dayOf := func(day int) time.Time {
+		return time.Date(firstDay.Year(), firstDay.Month(), day, 0, 0, 0, 0, firstDay.Location())
+	}
+
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, day := range r.ByMonthDay {
+			if day < 0 {
+				day = lastDay + day + 1
+			}
+
+			if day >= 1 && day <= lastDay {
+				result = append(result, dayOf(day))
+			}
+		}
+	case len(r.ByDay) > 0 && r.Freq == FreqMonthly:
+		for _, byDay := range r.ByDay {
+			var matching []time.Time
+
+			for day := 1; day <= lastDay; day++ {
+				if dayOf(day).Weekday() == byDay.Weekday {
+					matching = append(matching, dayOf(day))
+				}
+			}
+
+			switch {
+			case byDay.Ordinal == 0:
+				result = append(result, matching...)
+			case byDay.Ordinal > 0 && byDay.Ordinal <= len(matching):
+				result = append(result, matching[byDay.Ordinal-1])
+			case byDay.Ordinal < 0 && -byDay.Ordinal <= len(matching):
+				result = append(result, matching[len(matching)+byDay.Ordinal])
+			}
+		}
+	case dtstart.Day() <= lastDay:
+		// the months without the day are skipped, as RFC 5545 says.
+		result = append(result, dayOf(dtstart.Day()))
+	}
+
+	return result
+}
+
+// dayMatches applies the BYMONTH and BYDAY filters of the frequencies they do not expand.
+func (r *RRule) dayMatches(day time.Time) bool {
+	if len(r.ByMonth) > 0 && r.Freq != FreqYearly && !slices.Contains(r.ByMonth, day.Month()) {
+		return false
+	}
+
+	if len(r.ByDay) > 0 && (r.Freq == FreqDaily || r.Freq == FreqYearly) {
+		return slices.ContainsFunc(r.ByDay, func(b ByDay) bool { return b.Weekday == day.Weekday() })
+	}
+
+	return true
+}
+
+func civilDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -1,9 +1,13 @@
 package myerrs
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 type StatusCodeError struct {
 	StatusCode int
+	RetryAfter time.Duration // from the Retry-After header, zero if absent.
 }
 
 func (sc *StatusCodeError) Error() string {
@@ -17,3 +21,12 @@ type PlatformError struct {
 func (pe *PlatformError) Error() string {
 	return fmt.Sprintf("platform returned error: (%s)", pe.Text)
 }
+
+// AuthError is returned when the platform rejects the token.
+type AuthError struct {
+	StatusCode int
+}
+
+func (ae *AuthError) Error() string {
+	return fmt.Sprintf("not authorized, status code: %d", ae.StatusCode)
+}
@@ -0,0 +1,103 @@
+package poller
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net"
+	"time"
+
+	"github.com/eldarbr/schoolsubscriber/internal/myerrs"
+	"github.com/eldarbr/schoolsubscriber/internal/timeranges"
+)
+
+const (
+	// periodLookaround is how far around now the period windows are expanded.
+	periodLookaround = 24 * time.Hour
+	// maxBackoffShift keeps the doubled delay from overflowing.
+	maxBackoffShift = 16
+)
+
+// Period overrides the interval while the rule is on, e.g. faster in the evening and slower at night.
+type Period struct {
+	Rule     timeranges.Rule
+	Interval time.Duration
+}
+
+// Poller picks the delay before the next poll. The interval of the first period containing now
+// applies, the base interval otherwise. The delay doubles on every consecutive transient error up
+// to MaxBackoff, and it is randomized by the Jitter fraction either way. A longer Retry-After of the
+// failed response wins.
+type Poller struct {
+	Interval   time.Duration
+	Jitter     float64 // e.g. 0.2 for ±20%.
+	MaxBackoff time.Duration
+	Periods    []Period
+
+	failures int
+}
+
+// Next records the result of the poll and returns the delay before the next one.
+func (p *Poller) Next(now time.Time, err error) time.Duration {
+	if Transient(err) {
+		p.failures++
+	} else {
+		p.failures = 0
+	}
+
+	delay := p.interval(now)
+
+	if p.failures > 0 {
+		backoff := delay << min(p.failures, maxBackoffShift)
+		if p.MaxBackoff > 0 {
+			backoff = min(backoff, max(p.MaxBackoff, delay))
+		}
+
+		delay = backoff
+	}
+
+	var statusErr *myerrs.StatusCodeError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > delay {
+		return statusErr.RetryAfter // the server asked for it, no jitter could make it shorter.
+	}
+
+	return p.jitter(delay)
+}
+
+// Failures returns the number of the consecutive transient errors.
+func (p *Poller) Failures() int {
+	return p.failures
+}
+
+func (p *Poller) interval(now time.Time) time.Duration {
+	for _, period := range p.Periods {
+		if timeranges.Contains(period.Rule.Windows(now, now.Add(-periodLookaround), now.Add(periodLookaround)), now) {
+			return period.Interval
+		}
+	}
+
+	return p.Interval
+}
+
+func (p *Poller) jitter(delay time.Duration) time.Duration {
+	if p.Jitter <= 0 {
+		return delay
+	}
+
+	factor := 1 + p.Jitter*(2*rand.Float64()-1) //nolint:gosec,mnd // not a secret, a factor in [1-j, 1+j).
+
+	return time.Duration(float64(delay) * factor)
+}
+
+// Transient reports whether the error is worth backing off: a bad status code or a network failure.
+func Transient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var (
+		statusErr *myerrs.StatusCodeError
+		netErr    net.Error
+	)
+
+	return errors.As(err, &statusErr) || errors.As(err, &netErr)
+}
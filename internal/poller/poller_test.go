@@ -0,0 +1,146 @@
+package poller
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/eldarbr/schoolsubscriber/internal/myerrs"
+	"github.com/eldarbr/schoolsubscriber/internal/timeranges"
+)
+
+var (
+	errServer   = &myerrs.StatusCodeError{StatusCode: 500, RetryAfter: 0}
+	errPlatform = &myerrs.PlatformError{Text: "no such slot"}
+)
+
+func TestPollerNext(t *testing.T) {
+	t.Parallel()
+
+	evening, err := timeranges.ParseWeekly("daily 18:00-20:00", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	noon := time.Date(2026, time.March, 2, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		poller Poller
+		now    time.Time
+		errs   []error // the results of the consecutive polls, the delay after the last one is checked.
+		want   time.Duration
+	}{
+		{
+			name:   "success",
+			poller: Poller{Interval: time.Minute},
+			errs:   []error{nil},
+			want:   time.Minute,
+		},
+		{
+			name:   "doubles on every transient error",
+			poller: Poller{Interval: time.Minute},
+			errs:   []error{errServer, errServer, &net.OpError{Op: "dial", Err: errors.New("refused")}},
+			want:   8 * time.Minute,
+		},
+		{
+			name:   "capped by MaxBackoff",
+			poller: Poller{Interval: time.Minute, MaxBackoff: 5 * time.Minute},
+			errs:   []error{errServer, errServer, errServer},
+			want:   5 * time.Minute,
+		},
+		{
+			name:   "MaxBackoff below the interval keeps the interval",
+			poller: Poller{Interval: time.Minute, MaxBackoff: time.Second},
+			errs:   []error{errServer},
+			want:   time.Minute,
+		},
+		{
+			name:   "the shift is bounded, the delay does not overflow",
+			poller: Poller{Interval: time.Minute},
+			errs:   repeat(errServer, 100),
+			want:   time.Minute << maxBackoffShift,
+		},
+		{
+			name:   "a success resets the failures",
+			poller: Poller{Interval: time.Minute},
+			errs:   []error{errServer, errServer, nil},
+			want:   time.Minute,
+		},
+		{
+			name:   "a platform error is not transient",
+			poller: Poller{Interval: time.Minute},
+			errs:   []error{errServer, errPlatform},
+			want:   time.Minute,
+		},
+		{
+			name:   "a longer Retry-After wins",
+			poller: Poller{Interval: time.Minute},
+			errs:   []error{&myerrs.StatusCodeError{StatusCode: 429, RetryAfter: time.Hour}},
+			want:   time.Hour,
+		},
+		{
+			name:   "a shorter Retry-After does not shorten the backoff",
+			poller: Poller{Interval: time.Minute},
+			errs:   []error{&myerrs.StatusCodeError{StatusCode: 429, RetryAfter: time.Second}},
+			want:   2 * time.Minute,
+		},
+		{
+			name:   "the period interval applies inside the period",
+			poller: Poller{Interval: time.Minute, Periods: []Period{{Rule: evening, Interval: 10 * time.Second}}},
+			now:    noon.Add(7 * time.Hour),
+			errs:   []error{nil},
+			want:   10 * time.Second,
+		},
+		{
+			name:   "the base interval applies outside the periods",
+			poller: Poller{Interval: time.Minute, Periods: []Period{{Rule: evening, Interval: 10 * time.Second}}},
+			now:    noon,
+			errs:   []error{nil},
+			want:   time.Minute,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			now := test.now
+			if now.IsZero() {
+				now = noon
+			}
+
+			var got time.Duration
+			for _, err := range test.errs {
+				got = test.poller.Next(now, err)
+			}
+
+			if got != test.want {
+				t.Fatalf("Next() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPollerJitter(t *testing.T) {
+	t.Parallel()
+
+	poller := Poller{Interval: time.Minute, Jitter: 0.2}
+
+	for range 1000 {
+		got := poller.Next(time.Now(), nil)
+		if got < 48*time.Second || got > 72*time.Second {
+			t.Fatalf("Next() = %v, want within ±20%% of a minute", got)
+		}
+	}
+}
+
+func repeat(err error, times int) []error {
+	result := make([]error, times)
+	for i := range result {
+		result[i] = err
+	}
+
+	return result
+}
@@ -0,0 +1,167 @@
+package schoolgql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/eldarbr/schoolsubscriber/internal/myerrs"
+)
+
+const (
+	DefaultEndpoint = `https://platform.21-school.ru/services/graphql`
+	clientTimeout   = time.Second * 15
+)
+
+type IBaseResponse interface {
+	GetErrorText() string
+}
+
+// Call is a request on its way through the middlewares.
+type Call struct {
+	Request *Request
+	Token   string
+	Result  IBaseResponse
+	URL     string // the REST url to GET instead of posting the request to the GraphQL endpoint.
+}
+
+// Handler sends the call.
+type Handler func(ctx context.Context, call *Call) error
+
+// Middleware wraps the handler, see NewClient.
+type Middleware func(next Handler) Handler
+
+// Client sends the requests to the platform. It keeps the connections alive between the requests,
+// so a single client should be shared.
+type Client struct {
+	httpClient      *http.Client
+	endpoint        string
+	header          http.Header
+	handler         Handler
+	contextURL      string
+	contextOverride EduContext
+	contextMutex    sync.Mutex
+	contextReady    bool
+}
+
+// NewClient creates a client of the endpoint, DefaultEndpoint if empty. A nil httpClient is replaced
+// by a new one with the default transport. The first middleware is the outermost one. The edu context
+// is fetched from the server of the endpoint, see ContextInfoURL and SetEduContext.
+func NewClient(httpClient *http.Client, endpoint string, middlewares ...Middleware) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{ //nolint:exhaustruct // leave defaults.
+			Timeout: clientTimeout,
+		}
+	}
+
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+
+	client := &Client{
+		httpClient:      httpClient,
+		endpoint:        endpoint,
+		header:          defaultHeader(),
+		handler:         nil,
+		contextURL:      ContextInfoURL(endpoint),
+		contextOverride: EduContext{},
+		contextMutex:    sync.Mutex{},
+		contextReady:    false,
+	}
+
+	client.handler = client.send
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		client.handler = middlewares[i](client.handler)
+	}
+
+	return client
+}
+
+// Header returns the headers added to every request. It should only be changed before the client is used.
+func (c *Client) Header() http.Header {
+	return c.header
+}
+
+func defaultHeader() http.Header {
+	header := http.Header{}
+
+	header.Set("Content-Type", "application/json")
+	header.Set("X-EDU-ROUTE-INFO", "v1")
+	header.Set("userrole", "STUDENT")
+	// the school ids are added from the edu context, see ensureContext.
+
+	return header
+}
+
+// Do sends the request through the middlewares and decodes the response into resultPlaceholder.
+func (c *Client) Do(ctx context.Context, req *Request, token string, resultPlaceholder IBaseResponse) error {
+	err := c.ensureContext(ctx, token)
+	if err != nil {
+		return fmt.Errorf("edu context (might be set in the config): %w", err)
+	}
+
+	return c.handler(ctx, &Call{Request: req, Token: token, Result: resultPlaceholder, URL: ""})
+}
+
+// send makes a single attempt of the call.
+func (c *Client) send(ctx context.Context, call *Call) error {
+	if call.URL != "" {
+		return c.sendGet(ctx, call)
+	}
+
+	jsonData, err := json.Marshal(call.Request)
+	if err != nil {
+		return fmt.Errorf("marshalling JSON: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(jsonData))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	/* meta */
+	httpReq.Header = c.header.Clone()
+
+	/* auth */
+	/* either of these */
+	// httpReq.AddCookie(&http.Cookie{Name: "tokenId", Value: token})
+	httpReq.Header.Set("Authorization", "Bearer "+call.Token)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("making request: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(call.Result)
+	if err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+
+	if errText := call.Result.GetErrorText(); errText != "" {
+		return &myerrs.PlatformError{Text: errText}
+	}
+
+	return nil
+}
+
+// statusError is an *myerrs.AuthError when the token is rejected, an *myerrs.StatusCodeError otherwise.
+func statusError(resp *http.Response) error {
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return &myerrs.AuthError{StatusCode: resp.StatusCode}
+	}
+
+	return &myerrs.StatusCodeError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
@@ -0,0 +1,219 @@
+package schoolgql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/eldarbr/schoolsubscriber/internal/schoolgql/queries"
+)
+
+const (
+	DefaultContextInfoURL = `https://edu.21-school.ru/services/rest/edu-context/context-info`
+	contextInfoPath       = `/services/rest/edu-context/context-info`
+	// OperationContextInfo names the context info fetch in the logs and the metrics.
+	OperationContextInfo queries.TOperationName = `contextInfo`
+)
+
+var ErrIncompleteContext = errors.New("edu context misses ids")
+
+// EduContext identifies the campus of the user, the platform expects it in the headers.
+type EduContext struct {
+	SchoolID  string
+	ProductID string
+	OrgUnitID string
+}
+
+func (e EduContext) complete() bool {
+	return e.SchoolID != "" && e.ProductID != "" && e.OrgUnitID != ""
+}
+
+// merge returns the context with the empty fields taken from other.
+func (e EduContext) merge(other EduContext) EduContext {
+	if e.SchoolID == "" {
+		e.SchoolID = other.SchoolID
+	}
+
+	if e.ProductID == "" {
+		e.ProductID = other.ProductID
+	}
+
+	if e.OrgUnitID == "" {
+		e.OrgUnitID = other.OrgUnitID
+	}
+
+	return e
+}
+
+// ContextInfoURL returns the context info url of the server of the GraphQL endpoint: DefaultContextInfoURL
+// for DefaultEndpoint, the same path on the host of any other endpoint, e.g. a mock.
+func ContextInfoURL(endpoint string) string {
+	if endpoint == "" || endpoint == DefaultEndpoint {
+		return DefaultContextInfoURL
+	}
+
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.Host == "" {
+		return DefaultContextInfoURL
+	}
+
+	return (&url.URL{Scheme: parsed.Scheme, Host: parsed.Host, Path: contextInfoPath}).String()
+}
+
+// SetEduContext makes the client fetch the context info from the url with the first request and
+// fill the headers from it, an empty url keeps the one of the endpoint, see ContextInfoURL. The non-empty
+// fields of override win, a complete override is not fetched. It should only be called before the
+// client is used.
+func (c *Client) SetEduContext(infoURL string, override EduContext) {
+	if infoURL != "" {
+		c.contextURL = infoURL
+	}
+
+	c.contextOverride = override
+	c.contextReady = false
+}
+
+// ensureContext fetches the context info once, a failed fetch is repeated with the next request.
+func (c *Client) ensureContext(ctx context.Context, token string) error {
+	c.contextMutex.Lock()
+	defer c.contextMutex.Unlock()
+
+	if c.contextReady {
+		return nil
+	}
+
+	edu := c.contextOverride
+	if !edu.complete() {
+		fetched, err := c.fetchContext(ctx, token)
+		if err != nil {
+			return err
+		}
+
+		edu = edu.merge(fetched)
+	}
+
+	if !edu.complete() {
+		return fmt.Errorf("%w: %+v", ErrIncompleteContext, edu)
+	}
+
+	c.header.Set("X-EDU-SCHOOL-ID", edu.SchoolID)
+	c.header.Set("X-EDU-PRODUCT-ID", edu.ProductID)
+	c.header.Set("X-Edu-Org-Unit-Id", edu.OrgUnitID)
+	c.header.Set("schoolid", edu.SchoolID)
+
+	c.contextReady = true
+
+	return nil
+}
+
+// contextEntry is an edu context of the user, a user studying in several campuses has several.
+type contextEntry struct {
+	SchoolID  string `json:"schoolId"`
+	ProductID string `json:"productId"`
+	OrgUnitID string `json:"orgUnitId"`
+	IsActive  bool   `json:"isActive"`
+}
+
+// contextInfo is the response of the context info endpoint: a list of the contexts or a single one.
+type contextInfo struct {
+	Entries []contextEntry
+}
+
+func (info *contextInfo) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err := json.Unmarshal(trimmed, &info.Entries)
+		if err != nil {
+			return fmt.Errorf("context list: %w", err)
+		}
+
+		return nil
+	}
+
+	var entry contextEntry
+
+	err := json.Unmarshal(data, &entry)
+	if err != nil {
+		return fmt.Errorf("context: %w", err)
+	}
+
+	info.Entries = []contextEntry{entry}
+
+	return nil
+}
+
+func (info *contextInfo) GetErrorText() string {
+	return ""
+}
+
+// context returns the ids of the active complete entry, or of the first complete one. The ids are
+// never mixed from several entries.
+func (info *contextInfo) context() EduContext {
+	var result EduContext
+
+	for _, entry := range info.Entries {
+		edu := EduContext{SchoolID: entry.SchoolID, ProductID: entry.ProductID, OrgUnitID: entry.OrgUnitID}
+		if !edu.complete() {
+			continue
+		}
+
+		if entry.IsActive {
+			return edu
+		}
+
+		if !result.complete() {
+			result = edu
+		}
+	}
+
+	return result
+}
+
+// fetchContext gets the context info through the middlewares, so it is retried and counted
+// like the GraphQL requests.
+func (c *Client) fetchContext(ctx context.Context, token string) (EduContext, error) {
+	info := &contextInfo{Entries: nil}
+
+	err := c.handler(ctx, &Call{
+		Request: &Request{OperationName: OperationContextInfo, Query: "", Variables: struct{}{}},
+		Token:   token,
+		Result:  info,
+		URL:     c.contextURL,
+	})
+	if err != nil {
+		return EduContext{}, fmt.Errorf("context info: %w", err)
+	}
+
+	return info.context(), nil
+}
+
+// sendGet makes a single attempt of the call to the REST url.
+func (c *Client) sendGet(ctx context.Context, call *Call) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, call.URL, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	httpReq.Header.Set("Authorization", "Bearer "+call.Token)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("making request: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(call.Result)
+	if err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+
+	return nil
+}
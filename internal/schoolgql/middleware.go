@@ -0,0 +1,77 @@
+package schoolgql
+
+import (
+	"context"
+	"log"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/eldarbr/schoolsubscriber/internal/schoolgql/queries"
+)
+
+// Logging is the middleware logging every call with its duration.
+func Logging(next Handler) Handler {
+	return func(ctx context.Context, call *Call) error {
+		start := time.Now()
+		err := next(ctx, call)
+
+		if err != nil {
+			log.Println("gql", call.Request.OperationName, time.Since(start).Round(time.Millisecond), "Err:", err)
+		} else {
+			log.Println("gql", call.Request.OperationName, time.Since(start).Round(time.Millisecond))
+		}
+
+		return err
+	}
+}
+
+// OperationStats are the metrics of an operation.
+type OperationStats struct {
+	Calls    int           `json:"calls"`
+	Failures int           `json:"failures"`
+	Duration time.Duration `json:"duration"` // total.
+}
+
+// Metrics counts the calls of every operation, its Middleware is to be given to the client.
+type Metrics struct {
+	mutex sync.Mutex
+	stats map[queries.TOperationName]OperationStats
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		mutex: sync.Mutex{},
+		stats: make(map[queries.TOperationName]OperationStats),
+	}
+}
+
+func (m *Metrics) Middleware(next Handler) Handler {
+	return func(ctx context.Context, call *Call) error {
+		start := time.Now()
+		err := next(ctx, call)
+
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+
+		stats := m.stats[call.Request.OperationName]
+		stats.Calls++
+		stats.Duration += time.Since(start)
+
+		if err != nil {
+			stats.Failures++
+		}
+
+		m.stats[call.Request.OperationName] = stats
+
+		return err
+	}
+}
+
+// Stats returns a copy of the metrics.
+func (m *Metrics) Stats() map[queries.TOperationName]OperationStats {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return maps.Clone(m.stats)
+}
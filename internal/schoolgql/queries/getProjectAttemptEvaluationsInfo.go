@@ -165,7 +165,13 @@ type ResponseGetProjectAttemptEvaluationsInfoByStudent struct {
 				// } `json:"codeReview"`
 
 				P2P []struct {
-					// Checklist any `json:"checklist"`
+					Checklist *struct {
+						EndTimeCheck *string `json:"endTimeCheck"`
+						Reviewer     *struct {
+							Login string `json:"login"`
+						} `json:"reviewer"`
+						StartTimeCheck *string `json:"startTimeCheck"`
+					} `json:"checklist"`
 					Status string `json:"status"`
 				} `json:"p2p"`
 
@@ -0,0 +1,134 @@
+package schoolgql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eldarbr/schoolsubscriber/internal/myerrs"
+)
+
+const (
+	maxAttempts    = 3
+	retryBaseDelay = 500 * time.Millisecond
+	// maxRetryAfter is the longest delay the server may ask for to be retried here, the error of
+	// a response asking for a longer wait is returned right away, and the caller backs off.
+	maxRetryAfter = 30 * time.Second
+)
+
+// AttemptsError reports how many attempts the request took before failing.
+type AttemptsError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *AttemptsError) Error() string {
+	return fmt.Sprintf("%d attempt(s): %s", e.Attempts, e.Err)
+}
+
+func (e *AttemptsError) Unwrap() error {
+	return e.Err
+}
+
+// IsMutation reports whether the request changes data on the platform.
+func (req *Request) IsMutation() bool {
+	return strings.HasPrefix(strings.TrimSpace(string(req.Query)), "mutation")
+}
+
+// retryable classifies the error of an attempt. The queries are retried on 429, 5xx and network
+// errors. The mutations are only retried when the platform surely did not process them: on 429
+// and when the connection was never established. Nothing is retried when the server asks to wait
+// longer than maxRetryAfter.
+func retryable(err error, mutation bool) bool {
+	var statusErr *myerrs.StatusCodeError
+	if errors.As(err, &statusErr) {
+		if statusErr.RetryAfter > maxRetryAfter {
+			return false
+		}
+
+		if statusErr.StatusCode == http.StatusTooManyRequests {
+			return true
+		}
+
+		return !mutation && statusErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+
+	var netErr net.Error
+
+	return !mutation && errors.As(err, &netErr)
+}
+
+// retryDelay doubles the delay with every attempt, the Retry-After of the response wins.
+func retryDelay(err error, attempt int) time.Duration {
+	var statusErr *myerrs.StatusCodeError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		return statusErr.RetryAfter
+	}
+
+	return retryBaseDelay << (attempt - 1)
+}
+
+// parseRetryAfter reads the header, either seconds or an HTTP date.
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second
+	}
+
+	if date, err := http.ParseTime(header); err == nil {
+		return max(date.Sub(now), 0)
+	}
+
+	return 0
+}
+
+// withRetry runs the attempt until it succeeds, fails for good or the attempts run out.
+func withRetry(ctx context.Context, mutation bool, attempt func() error) error {
+	for num := 1; ; num++ {
+		err := attempt()
+		if err == nil {
+			return nil
+		}
+
+		if num >= maxAttempts || !retryable(err, mutation) {
+			return &AttemptsError{Attempts: num, Err: err}
+		}
+
+		timer := time.NewTimer(retryDelay(err, num))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return &AttemptsError{Attempts: num, Err: errors.Join(err, ctx.Err())}
+		case <-timer.C:
+		}
+	}
+}
+
+// Retry is the middleware retrying the calls when that is safe, see retryable. The error of
+// a failed call is an *AttemptsError.
+func Retry(next Handler) Handler {
+	return func(ctx context.Context, call *Call) error {
+		return withRetry(ctx, call.Request.IsMutation(), func() error {
+			return next(ctx, call)
+		})
+	}
+}
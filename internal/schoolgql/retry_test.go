@@ -0,0 +1,176 @@
+package schoolgql
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/eldarbr/schoolsubscriber/internal/myerrs"
+)
+
+func statusErr(code int, retryAfter time.Duration) error {
+	return &myerrs.StatusCodeError{StatusCode: code, RetryAfter: retryAfter}
+}
+
+func TestRetryable(t *testing.T) {
+	t.Parallel()
+
+	dialErr := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+	readErr := &net.OpError{Op: "read", Err: errors.New("connection reset")}
+
+	tests := []struct {
+		name     string
+		err      error
+		query    bool // retried as a query.
+		mutation bool // retried as a mutation.
+	}{
+		{name: "429", err: statusErr(429, 0), query: true, mutation: true},
+		{name: "429 with a short Retry-After", err: statusErr(429, maxRetryAfter), query: true, mutation: true},
+		{name: "429 with a long Retry-After", err: statusErr(429, maxRetryAfter+time.Second)},
+		{name: "503", err: statusErr(503, 0), query: true},
+		{name: "503 with a long Retry-After", err: statusErr(503, time.Hour)},
+		{name: "400", err: statusErr(400, 0)},
+		{name: "auth", err: &myerrs.AuthError{StatusCode: 401}},
+		{name: "platform", err: &myerrs.PlatformError{Text: "slot is taken"}},
+		{name: "dial", err: dialErr, query: true, mutation: true},
+		{name: "connection lost", err: readErr, query: true},
+		{name: "cancelled", err: context.Canceled},
+		{name: "deadline", err: context.DeadlineExceeded},
+		{name: "wrapped 429", err: &AttemptsError{Attempts: 1, Err: statusErr(429, 0)}, query: true, mutation: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := retryable(test.err, false); got != test.query {
+				t.Errorf("retryable(query) = %v, want %v", got, test.query)
+			}
+
+			if got := retryable(test.err, true); got != test.mutation {
+				t.Errorf("retryable(mutation) = %v, want %v", got, test.mutation)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		err     error
+		attempt int
+		want    time.Duration
+	}{
+		{name: "first", err: statusErr(503, 0), attempt: 1, want: retryBaseDelay},
+		{name: "doubles", err: statusErr(503, 0), attempt: 3, want: 4 * retryBaseDelay},
+		{name: "Retry-After wins", err: statusErr(429, 7*time.Second), attempt: 1, want: 7 * time.Second},
+		{name: "network", err: &net.OpError{Op: "dial", Err: errors.New("refused")}, attempt: 2, want: 2 * retryBaseDelay},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := retryDelay(test.err, test.attempt); got != test.want {
+				t.Fatalf("retryDelay() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, time.March, 2, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{header: "", want: 0},
+		{header: "120", want: 2 * time.Minute},
+		{header: "-5", want: 0},
+		{header: "Mon, 02 Mar 2026 12:00:30 GMT", want: 30 * time.Second},
+		{header: "Mon, 02 Mar 2026 11:00:00 GMT", want: 0},
+		{header: "soon", want: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.header, func(t *testing.T) {
+			t.Parallel()
+
+			if got := parseRetryAfter(test.header, now); got != test.want {
+				t.Fatalf("parseRetryAfter(%q) = %v, want %v", test.header, got, test.want)
+			}
+		})
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		mutation bool
+		errs     []error // the results of the consecutive attempts, nil after the list.
+		attempts int
+		fails    bool
+	}{
+		{name: "succeeds after a retry", errs: []error{statusErr(503, time.Millisecond)}, attempts: 2},
+		{
+			name:     "gives up after the max attempts",
+			errs:     []error{statusErr(503, time.Millisecond), statusErr(503, time.Millisecond), statusErr(503, 0)},
+			attempts: maxAttempts,
+			fails:    true,
+		},
+		{name: "mutation not retried on 503", mutation: true, errs: []error{statusErr(503, 0)}, attempts: 1, fails: true},
+		{name: "mutation retried on 429", mutation: true, errs: []error{statusErr(429, time.Millisecond)}, attempts: 2},
+		{name: "long Retry-After left to the caller", errs: []error{statusErr(429, time.Hour)}, attempts: 1, fails: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			attempts := 0
+
+			err := withRetry(context.Background(), test.mutation, func() error {
+				attempts++
+				if attempts <= len(test.errs) {
+					return test.errs[attempts-1]
+				}
+
+				return nil
+			})
+
+			if attempts != test.attempts {
+				t.Fatalf("attempts = %d, want %d", attempts, test.attempts)
+			}
+
+			if test.fails != (err != nil) {
+				t.Fatalf("withRetry() = %v, fails %v", err, test.fails)
+			}
+
+			var attemptsErr *AttemptsError
+			if err != nil && (!errors.As(err, &attemptsErr) || attemptsErr.Attempts != attempts) {
+				t.Fatalf("withRetry() = %v, want an *AttemptsError of %d attempt(s)", err, attempts)
+			}
+		})
+	}
+}
+
+func TestWithRetryCancelled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := withRetry(ctx, false, func() error { return statusErr(503, maxRetryAfter) })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("withRetry() = %v, want the cancellation", err)
+	}
+}
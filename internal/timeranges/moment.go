@@ -0,0 +1,106 @@
+package timeranges
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	MomentNow      = "now"
+	MomentToday    = "today"
+	MomentTomorrow = "tomorrow"
+)
+
+var ErrBadMoment = errors.New("malformed moment")
+
+// Moment is a point in time evaluated against the current time.
+type Moment interface {
+	At(now time.Time) time.Time
+}
+
+// AbsoluteMoment does not depend on the current time.
+type AbsoluteMoment time.Time
+
+func (m AbsoluteMoment) At(_ time.Time) time.Time {
+	return time.Time(m)
+}
+
+// RelativeMoment is "now", the midnight of "today" or "tomorrow" with an optional time
+// of day, shifted by the Offset. The days are taken in the Location.
+type RelativeMoment struct {
+	Base     string
+	Clock    time.Duration // since midnight, ignored for "now".
+	Offset   time.Duration
+	Location *time.Location
+}
+
+func (m RelativeMoment) At(now time.Time) time.Time {
+	loc := m.Location
+	if loc == nil {
+		loc = time.Local
+	}
+
+	now = now.In(loc)
+	result := now
+
+	switch m.Base {
+	case MomentToday:
+		result = atClock(now, m.Clock)
+	case MomentTomorrow:
+		result = atClock(time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, loc), m.Clock)
+	}
+
+	return result.Add(m.Offset)
+}
+
+// ParseMoment parses an absolute "2006-01-02 15:04:05" date-time or a relative moment
+// like "now+2h", "now - 30m", "today 18:00" or "tomorrow 09:00+1h30m".
+func ParseMoment(str string, loc *time.Location) (Moment, error) {
+	str = strings.TrimSpace(str)
+
+	absolute, err := time.ParseInLocation(time.DateTime, str, loc)
+	if err == nil {
+		return AbsoluteMoment(absolute), nil
+	}
+
+	result := RelativeMoment{Location: loc}
+	lower := strings.ToLower(str)
+
+	for _, base := range []string{MomentNow, MomentToday, MomentTomorrow} {
+		if strings.HasPrefix(lower, base) {
+			result.Base = base
+			lower = strings.TrimSpace(strings.TrimPrefix(lower, base))
+
+			break
+		}
+	}
+
+	if result.Base == "" {
+		return nil, fmt.Errorf("%w: %q, expected a date-time, now, today or tomorrow", ErrBadMoment, str)
+	}
+
+	if result.Base != MomentNow && lower != "" && lower[0] != '+' && lower[0] != '-' {
+		clock, rest, _ := strings.Cut(lower, " ")
+		if idx := strings.IndexAny(clock, "+-"); idx >= 0 {
+			clock, rest = clock[:idx], clock[idx:]+rest
+		}
+
+		result.Clock, err = ParseClock(clock)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %w", ErrBadMoment, str, err)
+		}
+
+		lower = strings.TrimSpace(rest)
+	}
+
+	if lower != "" {
+		result.Offset, err = time.ParseDuration(strings.ReplaceAll(lower, " ", ""))
+		if err != nil || (lower[0] != '+' && lower[0] != '-') {
+			return nil, fmt.Errorf("%w: %q, expected an offset like +2h", ErrBadMoment, str)
+		}
+	}
+
+	return result, nil
+}
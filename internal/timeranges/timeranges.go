@@ -0,0 +1,318 @@
+package timeranges
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	minutesPerHour = 60
+	hoursPerDay    = 24
+	daysPerWeek    = 7
+)
+
+var (
+	ErrBadClock   = errors.New("malformed time of day")
+	ErrBadWeekday = errors.New("malformed weekday")
+	ErrBadWeekly  = errors.New("malformed weekly rule")
+)
+
+// Rule produces the concrete time ranges intersecting [from, to), the relative
+// rules are evaluated at now.
+type Rule interface {
+	Windows(now, from, to time.Time) [][2]time.Time
+}
+
+// Absolute is a fixed time range.
+type Absolute [2]time.Time
+
+// Windows returns the range as is, the schedule clips it.
+func (a Absolute) Windows(_, _, _ time.Time) [][2]time.Time {
+	return [][2]time.Time{a}
+}
+
+// Relative is a range between two moments re-evaluated every time.
+type Relative struct {
+	Start Moment
+	End   Moment
+}
+
+func (r Relative) Windows(now, _, _ time.Time) [][2]time.Time {
+	return [][2]time.Time{{r.Start.At(now), r.End.At(now)}}
+}
+
+// Weekly repeats a time of day window on the chosen weekdays. The window is built from
+// the wall clock in Location, so it keeps its local hours across the DST changes.
+type Weekly struct {
+	Days     [daysPerWeek]bool // indexed by time.Weekday.
+	Start    time.Duration     // since midnight.
+	End      time.Duration     // since midnight, an End not after Start ends on the next day.
+	Location *time.Location
+}
+
+func (w Weekly) Windows(_, from, to time.Time) [][2]time.Time {
+	loc := w.Location
+	if loc == nil {
+		loc = time.Local
+	}
+
+	result := [][2]time.Time{}
+
+	localFrom := from.In(loc)
+	// a window started the day before might still be running.
+	day := time.Date(localFrom.Year(), localFrom.Month(), localFrom.Day()-1, 0, 0, 0, 0, loc)
+
+	for ; day.Before(to); day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, loc) {
+		if !w.Days[day.Weekday()] {
+			continue
+		}
+
+		start := atClock(day, w.Start)
+
+		endDay := day
+		if w.End <= w.Start {
+			endDay = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, loc)
+		}
+
+		end := atClock(endDay, w.End)
+
+		if start.Before(from) {
+			start = from
+		}
+
+		if end.After(to) {
+			end = to
+		}
+
+		if start.Before(end) {
+			result = append(result, [2]time.Time{start, end})
+		}
+	}
+
+	return result
+}
+
+// Schedule expands the rules over a rolling horizon. The ranges are clipped to
+// start not earlier than MinLead from now. The recurring ranges are clipped to end
+// within the Horizon, the Absolute and Relative ones only with ClipOneOff.
+type Schedule struct {
+	Rules      []Rule
+	MinLead    time.Duration
+	Horizon    time.Duration
+	ClipOneOff bool
+}
+
+// Windows returns the ranges of all the rules for the horizon starting at now, along with
+// the index of the rule every range comes from. The rules order is kept, so that the rule
+// index reflects the rule priority.
+func (s Schedule) Windows(now time.Time) ([][2]time.Time, []int) {
+	from, to := now.Add(s.MinLead), now.Add(s.Horizon)
+	result := [][2]time.Time{}
+	rules := []int{}
+
+	for ruleIndex, rule := range s.Rules {
+		clip := s.ClipOneOff || !oneOff(rule)
+
+		for _, window := range rule.Windows(now, from, to) {
+			if window[0].Before(from) {
+				window[0] = from
+			}
+
+			if clip && window[1].After(to) {
+				window[1] = to
+			}
+
+			if window[0].Before(window[1]) {
+				result = append(result, window)
+				rules = append(rules, ruleIndex)
+			}
+		}
+	}
+
+	return result, rules
+}
+
+// oneOff reports whether the rule is a single range rather than a recurring one.
+func oneOff(rule Rule) bool {
+	switch rule.(type) {
+	case Absolute, Relative:
+		return true
+	default:
+		return false
+	}
+}
+
+// ParseWeekly parses a rule like "Mon-Fri 18:00-23:00", "Sat,Sun 10:00-14:00" or "daily 22:00-02:00".
+func ParseWeekly(str string, loc *time.Location) (Weekly, error) {
+	result := Weekly{Location: loc}
+
+	fields := strings.Fields(str)
+	if len(fields) != 2 { //nolint:mnd // days and hours.
+		return result, fmt.Errorf("%w: %q, expected \"<days> <HH:MM>-<HH:MM>\"", ErrBadWeekly, str)
+	}
+
+	days, err := ParseDays(fields[0])
+	if err != nil {
+		return result, fmt.Errorf("%w: %q: %w", ErrBadWeekly, str, err)
+	}
+
+	result.Days = days
+
+	clockFrom, clockTo, ok := strings.Cut(fields[1], "-")
+	if !ok {
+		return result, fmt.Errorf("%w: %q, expected \"<HH:MM>-<HH:MM>\"", ErrBadWeekly, str)
+	}
+
+	result.Start, err = ParseClock(clockFrom)
+	if err != nil {
+		return result, fmt.Errorf("%w: %q: %w", ErrBadWeekly, str, err)
+	}
+
+	result.End, err = ParseClock(clockTo)
+	if err != nil {
+		return result, fmt.Errorf("%w: %q: %w", ErrBadWeekly, str, err)
+	}
+
+	return result, nil
+}
+
+// ParseDays parses "daily", a weekday, a range like "Mon-Fri" (wrapping like "Fri-Mon" is fine)
+// or a comma separated list of those.
+func ParseDays(str string) ([daysPerWeek]bool, error) {
+	var result [daysPerWeek]bool
+
+	for _, part := range strings.Split(str, ",") {
+		part = strings.TrimSpace(part)
+
+		if strings.EqualFold(part, "daily") {
+			for i := range result {
+				result[i] = true
+			}
+
+			continue
+		}
+
+		first, last, isRange := strings.Cut(part, "-")
+
+		firstDay, err := ParseWeekday(first)
+		if err != nil {
+			return result, err
+		}
+
+		lastDay := firstDay
+		if isRange {
+			lastDay, err = ParseWeekday(last)
+			if err != nil {
+				return result, err
+			}
+		}
+
+		for day := firstDay; ; day = (day + 1) % daysPerWeek {
+			result[day] = true
+
+			if day == lastDay {
+				break
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// ParseWeekday accepts the full or the three-letter english weekday name in any case.
+func ParseWeekday(str string) (time.Weekday, error) {
+	str = strings.ToLower(strings.TrimSpace(str))
+
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if str == name || str == name[:3] {
+			return day, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %q", ErrBadWeekday, str)
+}
+
+// ParseClock parses "HH:MM" into the duration since midnight, "24:00" is the end of the day.
+func ParseClock(str string) (time.Duration, error) {
+	hoursStr, minutesStr, ok := strings.Cut(strings.TrimSpace(str), ":")
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrBadClock, str)
+	}
+
+	hours, err := strconv.Atoi(hoursStr)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrBadClock, str)
+	}
+
+	minutes, err := strconv.Atoi(minutesStr)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrBadClock, str)
+	}
+
+	if hours < 0 || minutes < 0 || minutes >= minutesPerHour || hours*minutesPerHour+minutes > hoursPerDay*minutesPerHour {
+		return 0, fmt.Errorf("%w: %q", ErrBadClock, str)
+	}
+
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+}
+
+// atClock returns the wall clock time on the day in its location.
+func atClock(day time.Time, clock time.Duration) time.Time {
+	hours := int(clock / time.Hour)
+	minutes := int(clock % time.Hour / time.Minute)
+
+	return time.Date(day.Year(), day.Month(), day.Day(), hours, minutes, 0, 0, day.Location())
+}
+
+// Merge returns the union of the ranges as sorted non-overlapping ranges.
+func Merge(ranges [][2]time.Time) [][2]time.Time {
+	sorted := slices.Clone(ranges)
+	slices.SortFunc(sorted, func(a, b [2]time.Time) int { return a[0].Compare(b[0]) })
+
+	result := make([][2]time.Time, 0, len(sorted))
+
+	for _, r := range sorted {
+		if !r[0].Before(r[1]) {
+			continue
+		}
+
+		if last := len(result) - 1; last >= 0 && !r[0].After(result[last][1]) {
+			if r[1].After(result[last][1]) {
+				result[last][1] = r[1]
+			}
+
+			continue
+		}
+
+		result = append(result, r)
+	}
+
+	return result
+}
+
+// Contains reports whether the moment is inside any of the ranges.
+func Contains(ranges [][2]time.Time, moment time.Time) bool {
+	for _, r := range ranges {
+		if !moment.Before(r[0]) && moment.Before(r[1]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Overlaps reports whether [start, end) intersects any of the ranges.
+func Overlaps(ranges [][2]time.Time, start, end time.Time) bool {
+	for _, r := range ranges {
+		if start.Before(r[1]) && r[0].Before(end) {
+			return true
+		}
+	}
+
+	return false
+}
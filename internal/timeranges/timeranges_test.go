@@ -0,0 +1,176 @@
+package timeranges
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+
+	return loc
+}
+
+func TestWeeklyWindows(t *testing.T) {
+	t.Parallel()
+
+	berlin := mustLocation(t, "Europe/Berlin")
+
+	tests := []struct {
+		name     string
+		rule     string
+		loc      *time.Location
+		from, to time.Time
+		want     [][2]time.Time
+	}{
+		{
+			// the clocks jump from 02:00 to 03:00, the window keeps its wall clock and lasts 2 hours.
+			name: "spring DST day",
+			rule: "Sun 01:00-04:00",
+			loc:  berlin,
+			from: time.Date(2026, time.March, 28, 0, 0, 0, 0, berlin),
+			to:   time.Date(2026, time.March, 30, 0, 0, 0, 0, berlin),
+			want: [][2]time.Time{{
+				time.Date(2026, time.March, 29, 0, 0, 0, 0, time.UTC),
+				time.Date(2026, time.March, 29, 2, 0, 0, 0, time.UTC),
+			}},
+		},
+		{
+			name: "autumn DST day",
+			rule: "Sun 18:00-20:00",
+			loc:  berlin,
+			from: time.Date(2026, time.October, 24, 0, 0, 0, 0, berlin),
+			to:   time.Date(2026, time.October, 26, 0, 0, 0, 0, berlin),
+			want: [][2]time.Time{{
+				time.Date(2026, time.October, 25, 17, 0, 0, 0, time.UTC),
+				time.Date(2026, time.October, 25, 19, 0, 0, 0, time.UTC),
+			}},
+		},
+		{
+			// the window of the day before is still running at from, the last one is cut at to.
+			name: "crossing midnight",
+			rule: "daily 22:00-02:00",
+			loc:  time.UTC,
+			from: time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2026, time.March, 3, 0, 0, 0, 0, time.UTC),
+			want: [][2]time.Time{
+				{time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC), time.Date(2026, time.March, 2, 2, 0, 0, 0, time.UTC)},
+				{time.Date(2026, time.March, 2, 22, 0, 0, 0, time.UTC), time.Date(2026, time.March, 3, 0, 0, 0, 0, time.UTC)},
+			},
+		},
+		{
+			name: "crossing midnight on the chosen day only",
+			rule: "Fri 23:00-01:00",
+			loc:  time.UTC,
+			from: time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2026, time.March, 9, 0, 0, 0, 0, time.UTC),
+			want: [][2]time.Time{
+				{time.Date(2026, time.March, 6, 23, 0, 0, 0, time.UTC), time.Date(2026, time.March, 7, 1, 0, 0, 0, time.UTC)},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			weekly, err := ParseWeekly(test.rule, test.loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := weekly.Windows(test.from, test.from, test.to)
+			if !slices.EqualFunc(got, test.want, sameWindow) {
+				t.Fatalf("Windows() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func sameWindow(a, b [2]time.Time) bool {
+	return a[0].Equal(b[0]) && a[1].Equal(b[1])
+}
+
+func TestParseMoment(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, time.March, 2, 15, 20, 0, 0, time.UTC)
+
+	tests := []struct {
+		moment string
+		want   time.Time
+		err    error
+	}{
+		{moment: "2026-03-10 12:00:00", want: time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)},
+		{moment: "now", want: now},
+		{moment: "now+2h", want: now.Add(2 * time.Hour)},
+		{moment: "now - 30m", want: now.Add(-30 * time.Minute)},
+		{moment: "today 18:00", want: time.Date(2026, time.March, 2, 18, 0, 0, 0, time.UTC)},
+		{moment: "Tomorrow", want: time.Date(2026, time.March, 3, 0, 0, 0, 0, time.UTC)},
+		{moment: "tomorrow 09:00+1h30m", want: time.Date(2026, time.March, 3, 10, 30, 0, 0, time.UTC)},
+		{moment: "tomorrow 02:00 -1h", want: time.Date(2026, time.March, 3, 1, 0, 0, 0, time.UTC)},
+		{moment: "yesterday", err: ErrBadMoment},
+		{moment: "now 18:00", err: ErrBadMoment},
+		{moment: "today 25:00", err: ErrBadMoment},
+	}
+
+	for _, test := range tests {
+		t.Run(test.moment, func(t *testing.T) {
+			t.Parallel()
+
+			moment, err := ParseMoment(test.moment, time.UTC)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("ParseMoment() error = %v, want %v", err, test.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := moment.At(now); !got.Equal(test.want) {
+				t.Fatalf("At() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestScheduleWindows(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, time.March, 2, 12, 0, 0, 0, time.UTC) // Monday.
+
+	weekly, err := ParseWeekly("daily 18:00-20:00", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	later := Absolute{now.Add(10 * 24 * time.Hour), now.Add(10*24*time.Hour + 2*time.Hour)}
+	schedule := Schedule{Rules: []Rule{weekly, later}, MinLead: time.Hour, Horizon: 2 * 24 * time.Hour, ClipOneOff: false}
+
+	windows, rules := schedule.Windows(now)
+	if len(windows) != 3 || !sameWindow(windows[2], later) {
+		t.Fatalf("Windows() = %v, want the 2 days of the weekly rule and the later range", windows)
+	}
+
+	if !slices.Equal(rules, []int{0, 0, 1}) {
+		t.Fatalf("Windows() rules = %v, want [0 0 1]", rules)
+	}
+
+	schedule.ClipOneOff = true
+
+	windows, rules = schedule.Windows(now)
+	if len(windows) != 2 || !slices.Equal(rules, []int{0, 0}) {
+		t.Fatalf("Windows() = %v %v, want the later range dropped", windows, rules)
+	}
+}
@@ -0,0 +1,172 @@
+package tokencache
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	appDir   = "schoolsubscriber"
+	fileName = "token.json"
+	dirMode  = 0o700
+	fileMode = 0o600
+)
+
+var (
+	ErrNoHome     = errors.New("neither XDG_STATE_HOME nor the home directory is known")
+	ErrBadJWT     = errors.New("malformed JWT")
+	ErrOtherLogin = errors.New("the cached token is of another login")
+	ErrUnsafeFile = errors.New("the token cache is not private to the user")
+)
+
+// Entry is a cached token.
+type Entry struct {
+	Login   string    `json:"login"`
+	Token   string    `json:"token"`
+	Expires time.Time `json:"expires"`
+}
+
+// ValidFor reports whether the token is still valid for at least the margin.
+func (e Entry) ValidFor(now time.Time, margin time.Duration) bool {
+	return e.Token != "" && now.Add(margin).Before(e.Expires)
+}
+
+// DefaultPath is $XDG_STATE_HOME/schoolsubscriber/token.json, XDG_STATE_HOME defaults to ~/.local/state.
+func DefaultPath() (string, error) {
+	stateHome := os.Getenv("XDG_STATE_HOME")
+	if stateHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", ErrNoHome, err)
+		}
+
+		stateHome = filepath.Join(home, ".local", "state")
+	}
+
+	return filepath.Join(stateHome, appDir, fileName), nil
+}
+
+// Load reads the entry of the login. A missing file is reported as os.ErrNotExist. The token is
+// stored in plain text, so on unix only a file owned by the user with the mode 0600 is trusted,
+// ErrUnsafeFile is returned otherwise. A file readable by the group or the others is removed as well.
+func Load(path, login string) (Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Entry{}, fmt.Errorf("open token cache: %w", err)
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return Entry{}, fmt.Errorf("stat token cache: %w", err)
+	}
+
+	err = checkPrivate(path, info)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return Entry{}, fmt.Errorf("read token cache: %w", err)
+	}
+
+	var entry Entry
+
+	err = json.Unmarshal(content, &entry)
+	if err != nil {
+		return Entry{}, fmt.Errorf("decode token cache: %w", err)
+	}
+
+	if entry.Login != login {
+		return Entry{}, ErrOtherLogin
+	}
+
+	return entry, nil
+}
+
+// Save replaces the file atomically, it is only readable by the user.
+func Save(path string, entry Entry) error {
+	err := os.MkdirAll(filepath.Dir(path), dirMode)
+	if err != nil {
+		return fmt.Errorf("create token cache dir: %w", err)
+	}
+
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encode token cache: %w", err)
+	}
+
+	// the temp file is created with 0600, see fileMode.
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp token cache: %w", err)
+	}
+
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(content)
+	if err != nil {
+		tmp.Close()
+
+		return fmt.Errorf("write token cache: %w", err)
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return fmt.Errorf("close temp token cache: %w", err)
+	}
+
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return fmt.Errorf("replace token cache: %w", err)
+	}
+
+	return nil
+}
+
+// Remove deletes the file, a missing file is fine.
+func Remove(path string) error {
+	err := os.Remove(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove token cache: %w", err)
+	}
+
+	return nil
+}
+
+// Expiry decodes the exp claim of the JWT, the signature is not verified.
+func Expiry(jwt string) (time.Time, error) {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 { //nolint:mnd // header, payload and signature.
+		return time.Time{}, ErrBadJWT
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: payload: %w", ErrBadJWT, err)
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+
+	err = json.Unmarshal(payload, &claims)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: claims: %w", ErrBadJWT, err)
+	}
+
+	if claims.Exp == 0 {
+		return time.Time{}, fmt.Errorf("%w: no exp claim", ErrBadJWT)
+	}
+
+	return time.Unix(claims.Exp, 0), nil
+}
@@ -0,0 +1,111 @@
+package tokencache
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func jwt(payload string) string {
+	return "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".signature"
+}
+
+func TestExpiry(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		token string
+		want  time.Time
+		err   bool
+	}{
+		{name: "exp claim", token: jwt(`{"sub":"login","exp":1772452800}`), want: time.Unix(1772452800, 0)},
+		{
+			name:  "padded payload",
+			token: "h." + base64.URLEncoding.EncodeToString([]byte(`{"exp":1772452800}`)) + ".s",
+			want:  time.Unix(1772452800, 0),
+		},
+		{name: "no exp claim", token: jwt(`{"sub":"login"}`), err: true},
+		{name: "two parts", token: "header.payload", err: true},
+		{name: "not base64", token: "h.@@@.s", err: true},
+		{name: "not JSON", token: jwt(`exp`), err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Expiry(test.token)
+			if test.err {
+				if !errors.Is(err, ErrBadJWT) {
+					t.Fatalf("Expiry() error = %v, want ErrBadJWT", err)
+				}
+
+				return
+			}
+
+			if err != nil || !got.Equal(test.want) {
+				t.Fatalf("Expiry() = %v, %v, want %v", got, err, test.want)
+			}
+		})
+	}
+}
+
+func TestEntryValidFor(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, time.March, 2, 12, 0, 0, 0, time.UTC)
+	entry := Entry{Login: "login", Token: "token", Expires: now.Add(10 * time.Minute)}
+
+	if !entry.ValidFor(now, 5*time.Minute) {
+		t.Fatal("ValidFor(5m) = false, want true")
+	}
+
+	if entry.ValidFor(now, 10*time.Minute) {
+		t.Fatal("ValidFor(10m) = true, want false")
+	}
+
+	if (Entry{Login: "login", Token: "", Expires: entry.Expires}).ValidFor(now, 0) {
+		t.Fatal("ValidFor() of an empty token = true, want false")
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state", "token.json")
+	entry := Entry{Login: "login", Token: "token", Expires: time.Date(2026, time.March, 2, 12, 0, 0, 0, time.UTC)}
+
+	_, err := Load(path, "login")
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Load() of a missing file error = %v, want os.ErrNotExist", err)
+	}
+
+	err = Save(path, entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Load(path, "login")
+	if err != nil || got.Token != entry.Token || !got.Expires.Equal(entry.Expires) {
+		t.Fatalf("Load() = %v, %v, want %v", got, err, entry)
+	}
+
+	_, err = Load(path, "other")
+	if !errors.Is(err, ErrOtherLogin) {
+		t.Fatalf("Load() of another login error = %v, want ErrOtherLogin", err)
+	}
+
+	err = Remove(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Remove(path)
+	if err != nil {
+		t.Fatalf("Remove() of a missing file = %v, want nil", err)
+	}
+}
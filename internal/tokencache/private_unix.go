@@ -0,0 +1,27 @@
+//go:build unix
+
+package tokencache
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkPrivate makes sure that no one but the user could read or replace the token.
+func checkPrivate(path string, info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("%w: %s is owned by another user", ErrUnsafeFile, path)
+	}
+
+	if info.Mode().Perm()&^fileMode != 0 {
+		return errors.Join(
+			fmt.Errorf("%w: %s has the mode %s, removed", ErrUnsafeFile, path, info.Mode().Perm()),
+			Remove(path),
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+//go:build unix
+
+package tokencache
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadPrivate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		mode    os.FileMode
+		owner   int // a uid other than the current one, zero for the current one.
+		err     error
+		removed bool
+	}{
+		{name: "private", mode: 0o600},
+		{name: "read-only", mode: 0o400},
+		{name: "group readable", mode: 0o640, err: ErrUnsafeFile, removed: true},
+		{name: "world readable", mode: 0o604, err: ErrUnsafeFile, removed: true},
+		{name: "another owner", mode: 0o600, owner: os.Getuid() + 1, err: ErrUnsafeFile},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "token.json")
+
+			err := Save(path, Entry{Login: "login", Token: "token", Expires: time.Now().Add(time.Hour)})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = os.Chmod(path, test.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.owner != 0 {
+				err = os.Chown(path, test.owner, -1)
+				if err != nil {
+					t.Skip("can not change the owner:", err)
+				}
+			}
+
+			_, err = Load(path, "login")
+			if !errors.Is(err, test.err) {
+				t.Fatalf("Load() error = %v, want %v", err, test.err)
+			}
+
+			_, err = os.Stat(path)
+			if removed := errors.Is(err, os.ErrNotExist); removed != test.removed {
+				t.Fatalf("the file removed = %v, want %v", removed, test.removed)
+			}
+		})
+	}
+}